@@ -1,24 +1,94 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/audio"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/config"
 	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/game"
 	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/gen"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/log"
 	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/messages"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/random"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/ui"
 	"time"
 )
 
 // This value is overridden at build time using `-ldflags`.
-var apiKey = "change me"
+var apiKey = config.UnsetApiKey
 
 func main() {
+	personaName := flag.String("persona", "", "the narrator persona: cosmic-horror (the default), fairy-tale, "+
+		"b-movie, incident-report or bestiary")
+	locale := flag.String("locale", "", "the locale that chooses the game's language, such as es_ES.UTF-8 or es "+
+		"(by default, the locale set in the environment)")
+	privacyMode := flag.Bool("privacy", false, "never send anything you enter over the network")
+	recordPath := flag.String("record", "", "record the backend's responses to the given cassette file")
+	replayPath := flag.String("replay", "", "replay the backend's responses from the given cassette file")
+	seed := flag.Uint64("seed", 0, "the seed for the game's random choices, to reproduce an earlier run")
+	flag.Parse()
+
 	_ = audio.Play(audio.DoubleBeepSoundEffect, nil, false)
 	time.Sleep(300 * time.Millisecond)
-	messageProvider := messages.NewMessageProvider()
-	creatureGenerator := gen.NewCreatureGenerator(messageProvider, apiKey)
-	teaProgram := tea.NewProgram(game.New(messageProvider, creatureGenerator), tea.WithAltScreen())
-	_, err := teaProgram.Run()
+	gameConfig, err := config.Load(apiKey)
+	if err != nil {
+		panic(err)
+	}
+	if *privacyMode {
+		gameConfig.PrivacyMode = true
+	}
+	if len(*recordPath) > 0 {
+		gameConfig.Backend.Record = *recordPath
+	}
+	if len(*replayPath) > 0 {
+		gameConfig.Backend.Replay = *replayPath
+	}
+	if *seed != 0 {
+		gameConfig.Seed = *seed
+	}
+	// A seed that's already set is being used to reproduce a run, so the prompts are drawn the way they were then.
+	replay := gameConfig.Seed != 0
+	if !replay {
+		gameConfig.Seed = random.NewSeed()
+	}
+	log.Logger.Print(fmt.Sprintf("func=\"main.main\", msg=\"Random seed chosen. Use -seed to reproduce this run.\", "+
+		"seed=\"%d\"", gameConfig.Seed))
+	randomSource := random.NewSource(gameConfig.Seed)
+	if len(*personaName) > 0 {
+		gameConfig.Persona = *personaName
+	}
+	persona, err := messages.ParsePersona(gameConfig.Persona)
+	if err != nil {
+		panic(err)
+	}
+	ui.SetPaletteByName(persona.PaletteHint())
+	if len(*locale) > 0 {
+		gameConfig.Locale = *locale
+	}
+	if len(gameConfig.Locale) == 0 {
+		gameConfig.Locale = messages.SystemLocale()
+	}
+	language := messages.LanguageFromLocale(gameConfig.Locale)
+	log.Logger.Print(fmt.Sprintf("func=\"main.main\", msg=\"Language chosen.\", locale=\"%s\", language=\"%s\"",
+		gameConfig.Locale, language))
+
+	packs, err := messages.LoadPacks(gameConfig.Packs)
+	if err != nil {
+		panic(err)
+	}
+	messageProvider, err := messages.NewMessageProvider(persona, language, packs, gameConfig.Ritual.Length.Offerings(),
+		randomSource.Stream("prompts"), gameConfig.Prompts.StateFile, gameConfig.Seed, replay)
+	if err != nil {
+		panic(err)
+	}
+	creatureGenerator, err := gen.NewCreatureGenerator(messageProvider, gameConfig)
+	if err != nil {
+		panic(err)
+	}
+	teaProgram := tea.NewProgram(game.New(messageProvider, creatureGenerator, gameConfig, randomSource),
+		tea.WithAltScreen())
+	_, err = teaProgram.Run()
 	if err != nil {
 		panic(err)
 	}
@@ -2,6 +2,7 @@ package audio
 
 import (
 	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/effects"
 	"github.com/gopxl/beep/v2/speaker"
 	"github.com/gopxl/beep/v2/wav"
 	"os"
@@ -16,6 +17,21 @@ var currentlyPlaying = map[SoundEffectFilename]bool{}
 var currentlyPlayingMutex sync.RWMutex
 var lastPlayedSegment = map[SoundEffectFilename]int{}
 var lastPlayedSegmentMutex sync.RWMutex
+var playbacks = map[SoundEffectFilename]*playback{}
+var playbacksMutex sync.Mutex
+
+// playback controls a sound effect that is currently playing.
+type playback struct {
+	ctrl   *beep.Ctrl
+	volume *effects.Volume
+}
+
+// fadeOutSteps is the number of steps in which FadeOut lowers the volume.
+const fadeOutSteps = 20
+
+// fadeOutVolume is the volume at which FadeOut stops the sound effect. The volume scale is logarithmic, with a base
+// of 2.
+const fadeOutVolume = -6
 
 // init initializes the audio.
 func init() {
@@ -29,6 +45,41 @@ func init() {
 // replace "%d" in the filename, in order to select the correct segment of the sound effect. If allowOverlap is true,
 // it will play the sound effect even if another instance of the same sound effect is already playing.
 func Play(filename SoundEffectFilename, fileSegmentIndex *int, allowOverlap bool) error {
+	return play(filename, fileSegmentIndex, allowOverlap, false)
+}
+
+// PlayLooping plays the given sound effect repeatedly, until it's stopped with FadeOut. It does nothing if the sound
+// effect is already playing.
+func PlayLooping(filename SoundEffectFilename) error {
+	return play(filename, nil, false, true)
+}
+
+// FadeOut gradually lowers the volume of the given sound effect over the given duration, then stops it. It does
+// nothing if the sound effect isn't playing. If multiple instances of the sound effect are playing, only the most
+// recent one is faded out.
+func FadeOut(filename SoundEffectFilename, duration time.Duration) {
+	currentPlayback := getPlayback(filename)
+	if currentPlayback == nil {
+		return
+	}
+
+	go func() {
+		for step := 1; step <= fadeOutSteps; step++ {
+			time.Sleep(duration / fadeOutSteps)
+			speaker.Lock()
+			currentPlayback.volume.Volume = float64(fadeOutVolume*step) / fadeOutSteps
+			speaker.Unlock()
+		}
+
+		speaker.Lock()
+		currentPlayback.ctrl.Streamer = nil
+		speaker.Unlock()
+	}()
+}
+
+// play plays the given sound effect, as described by Play. If loop is true, the sound effect repeats until it's
+// stopped.
+func play(filename SoundEffectFilename, fileSegmentIndex *int, allowOverlap, loop bool) error {
 	originalFilename := filename
 	if fileSegmentIndex != nil {
 		filename = filename.Segment(*fileSegmentIndex)
@@ -63,11 +114,21 @@ func Play(filename SoundEffectFilename, fileSegmentIndex *int, allowOverlap bool
 		return err
 	}
 
+	var source beep.Streamer = streamer
+	if loop {
+		source = beep.Loop(-1, streamer)
+	}
+
 	// Make sure the streamer's sample rate matches the speaker's sample rate.
-	resampledStreamer := beep.Resample(4, format.SampleRate, speakerSampleRate, streamer)
+	resampledStreamer := beep.Resample(4, format.SampleRate, speakerSampleRate, source)
 
-	speaker.Play(beep.Seq(resampledStreamer, beep.Callback(func() {
+	currentPlayback := &playback{volume: &effects.Volume{Streamer: resampledStreamer, Base: 2}}
+	currentPlayback.ctrl = &beep.Ctrl{Streamer: currentPlayback.volume}
+	setPlayback(originalFilename, currentPlayback)
+
+	speaker.Play(beep.Seq(currentPlayback.ctrl, beep.Callback(func() {
 		setCurrentlyPlaying(originalFilename, false)
+		removePlayback(originalFilename, currentPlayback)
 		_ = streamer.Close()
 	})))
 
@@ -99,6 +160,30 @@ func getCurrentlyPlaying(filename SoundEffectFilename) bool {
 	return currentlyPlaying[filename]
 }
 
+// setPlayback sets the playback of the given sound effect.
+func setPlayback(filename SoundEffectFilename, value *playback) {
+	playbacksMutex.Lock()
+	defer playbacksMutex.Unlock()
+	playbacks[filename] = value
+}
+
+// getPlayback returns the playback of the given sound effect, or nil if it isn't playing.
+func getPlayback(filename SoundEffectFilename) *playback {
+	playbacksMutex.Lock()
+	defer playbacksMutex.Unlock()
+	return playbacks[filename]
+}
+
+// removePlayback removes the given playback of the given sound effect, unless it has already been replaced by a newer
+// playback.
+func removePlayback(filename SoundEffectFilename, value *playback) {
+	playbacksMutex.Lock()
+	defer playbacksMutex.Unlock()
+	if playbacks[filename] == value {
+		delete(playbacks, filename)
+	}
+}
+
 // setLastSegmentPlayed sets the last segment played for the given sound effect.
 func setLastSegmentPlayed(filename SoundEffectFilename, value int) {
 	lastPlayedSegmentMutex.Lock()
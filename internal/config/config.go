@@ -0,0 +1,505 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// BackendType identifies the kind of backend used to generate creature descriptions.
+type BackendType string
+
+const (
+	OpenAiBackend           BackendType = "openai"
+	OpenAiCompatibleBackend BackendType = "openai-compatible"
+	OfflineBackend          BackendType = "offline"
+	FakeBackend             BackendType = "fake"
+)
+
+// ModeratorType identifies the kind of moderator used to check generated text before it's shown.
+type ModeratorType string
+
+const (
+	NoModerator        ModeratorType = "none"
+	BlocklistModerator ModeratorType = "blocklist"
+	OpenAiModerator    ModeratorType = "openai"
+)
+
+// RitualLength identifies how many offerings the player makes during the ritual.
+type RitualLength string
+
+const (
+	ShortRitual    RitualLength = "short"
+	StandardRitual RitualLength = "standard"
+	LongRitual     RitualLength = "long"
+)
+
+// ritualOfferings contains the number of offerings made during a ritual of each length.
+var ritualOfferings = map[RitualLength]int{
+	ShortRitual:    3,
+	StandardRitual: 5,
+	LongRitual:     7,
+}
+
+// Offerings returns the number of offerings made during a ritual of the length, or zero if the length isn't valid.
+func (l RitualLength) Offerings() int {
+	return ritualOfferings[l]
+}
+
+// UnsetApiKey is the placeholder API key used when no key was provided at build time.
+const UnsetApiKey = "change me"
+
+// configFilename is the name of the configuration file, which is read from the directory containing the executable.
+const configFilename = "summon.json"
+
+// stateFilename is the name of the default backend health state file, which is written to the directory containing
+// the executable.
+const stateFilename = "summon-state.json"
+
+// packsDirectoryName is the name of the default directory that prompt packs are loaded from, in the directory
+// containing the executable.
+const packsDirectoryName = "packs"
+
+// promptStateFilename is the name of the default prompt state file, which is written to the directory containing the
+// executable.
+const promptStateFilename = "summon-prompts.json"
+
+// maxCandidates is the maximum number of candidate creatures generated at once.
+const maxCandidates = 5
+
+// statsFilename is the name of the default usage statistics file, which is written to the directory containing the
+// executable.
+const statsFilename = "summon-stats.json"
+
+// Backend contains the configuration for a single description backend.
+type Backend struct {
+	Type    BackendType `json:"type"`
+	ApiKey  string      `json:"apiKey"`
+	BaseUrl string      `json:"baseUrl"`
+	Model   string      `json:"model"`
+
+	// Record is the path of a cassette file that the backend's requests and responses are recorded to. It's only
+	// supported by the OpenAI and OpenAI-compatible backends.
+	Record string `json:"record"`
+
+	// Replay is the path of a cassette file that responses are replayed from, instead of sending requests to the
+	// backend. When it's set, the backend's type is ignored.
+	Replay string `json:"replay"`
+}
+
+// Health contains the configuration for remembering which backends have recently failed, so they can be skipped.
+type Health struct {
+	// Cooldown is how long a backend is skipped for after it fails.
+	Cooldown Duration `json:"cooldown"`
+
+	// StateFile is the path of the file that recent failures are remembered in, so they're also skipped in later
+	// sessions. If it isn't configured, summon-state.json in the directory containing the executable is used.
+	StateFile string `json:"stateFile"`
+}
+
+// Sampling contains the sampling parameters used when generating text with a language model. A zero value means the
+// backend's default is used.
+type Sampling struct {
+	Temperature float32 `json:"temperature"`
+	TopP        float32 `json:"topP"`
+	MaxTokens   int     `json:"maxTokens"`
+}
+
+// Summoning contains the configuration for the summoning phase of the game.
+type Summoning struct {
+	// MinDuration is the minimum time the summoning lasts, even if the creature is ready sooner.
+	MinDuration Duration `json:"minDuration"`
+
+	// MaxDuration is the maximum time to wait for the creature before giving up on the backend.
+	MaxDuration Duration `json:"maxDuration"`
+
+	// Candidates is the number of creatures generated at once, for the player to choose between. A value of 1 means
+	// the creature is shown as soon as it begins to appear, without a choice.
+	Candidates int `json:"candidates"`
+}
+
+// Ritual contains the configuration for the ritual, during which the player makes offerings to shape the creature.
+type Ritual struct {
+	// Length is how many offerings the player makes.
+	Length RitualLength `json:"length"`
+}
+
+// Prompts contains the configuration for the prompts shown to the player during the ritual.
+type Prompts struct {
+	// Adaptive indicates that each prompt after the first is generated from the player's previous responses.
+	Adaptive bool `json:"adaptive"`
+
+	// Timeout is how long to wait for a generated prompt before falling back to a static one.
+	Timeout Duration `json:"timeout"`
+
+	// StateFile is the path of the file that the prompts not yet asked are remembered in, so that prompts aren't
+	// repeated in later sessions until every prompt has been asked. It also remembers which prompts recent sessions
+	// started with, so they can be replayed with their seeds. If it isn't configured, summon-prompts.json in the
+	// directory containing the executable is used.
+	StateFile string `json:"stateFile"`
+}
+
+// Packs contains the configuration for prompt packs, which provide the ritual's prompts and can replace the game's
+// messages.
+type Packs struct {
+	// Directory is the directory that packs are loaded from, in addition to the built-in ones. If it isn't configured,
+	// the packs directory next to the executable is used.
+	Directory string `json:"directory"`
+
+	// Enabled are the names of the packs to use. If it's empty, every pack is used, except those that are disabled.
+	Enabled []string `json:"enabled"`
+
+	// Disabled are the names of the packs not to use.
+	Disabled []string `json:"disabled"`
+}
+
+// Moderation contains the configuration for checking generated text before it's shown to the player.
+type Moderation struct {
+	Type ModeratorType `json:"type"`
+
+	// FamilyFriendly tightens the moderator's thresholds, so that anything gory or violent is flagged.
+	FamilyFriendly bool `json:"familyFriendly"`
+
+	// ApiKey is the API key for the OpenAI moderator. If it's empty, the backend's API key is used.
+	ApiKey string `json:"apiKey"`
+
+	// BlockedWords are flagged by the blocklist moderator, in addition to its own list.
+	BlockedWords []string `json:"blockedWords"`
+}
+
+// Usage contains the configuration for tracking the tokens used by the backend, and their estimated cost.
+type Usage struct {
+	// StatsFile is the path of the file that usage statistics are written to. If it isn't configured, summon-stats.json
+	// in the directory containing the executable is used.
+	StatsFile string `json:"statsFile"`
+
+	// Budget is the maximum estimated cost of all sessions combined, in US dollars. Once it's exceeded, the offline
+	// backend is used instead. Zero means there's no limit.
+	Budget float64 `json:"budget"`
+
+	// SessionBudget is the maximum estimated cost of a single session, in US dollars. Once it's exceeded, the offline
+	// backend is used for the rest of the session. Zero means there's no limit.
+	SessionBudget float64 `json:"sessionBudget"`
+
+	// Prices are the prices of models, keyed by model name, in addition to the built-in ones. A price applies to every
+	// model whose name begins with its key, such as "gpt-4o-2024-05-13" for "gpt-4o".
+	Prices map[string]Price `json:"prices"`
+}
+
+// Price is the price of using a model, in US dollars per million tokens.
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Conversation contains the configuration for the conversation with the creature after the summoning.
+type Conversation struct {
+	// Turns is the number of messages the player can send to the creature. Zero disables the conversation.
+	Turns int `json:"turns"`
+
+	// Timeout is how long to wait for each of the creature's replies before falling back to an offline reply.
+	Timeout Duration `json:"timeout"`
+}
+
+// Config contains the configuration for the game.
+type Config struct {
+	// Persona is the name of the narrator persona. An empty name means the default persona is used.
+	Persona string `json:"persona"`
+
+	// Locale is the locale that chooses the language of the game, such as "es_ES.UTF-8" or "es". An empty locale means
+	// the locale set in the environment (through LC_ALL, LC_MESSAGES or LANG) is used.
+	Locale string `json:"locale"`
+
+	// PrivacyMode guarantees that nothing the player enters leaves the machine, by only allowing local backends.
+	PrivacyMode bool `json:"privacyMode"`
+
+	// Seed is the seed for the game's random choices, so that a run can be reproduced. Zero means a seed is chosen at
+	// random.
+	Seed uint64 `json:"seed"`
+
+	Backend Backend `json:"backend"`
+
+	// Fallbacks are the backends to try, in order, if the backend fails. The offline backend is always the final
+	// fallback.
+	Fallbacks []Backend `json:"fallbacks"`
+
+	Health       Health       `json:"health"`
+	Sampling     Sampling     `json:"sampling"`
+	Summoning    Summoning    `json:"summoning"`
+	Ritual       Ritual       `json:"ritual"`
+	Prompts      Prompts      `json:"prompts"`
+	Packs        Packs        `json:"packs"`
+	Conversation Conversation `json:"conversation"`
+	Moderation   Moderation   `json:"moderation"`
+	Usage        Usage        `json:"usage"`
+}
+
+// Duration is a time.Duration that is written in the configuration file as a string, such as "10s".
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler by parsing the duration from a string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Load returns the game's configuration. Defaults are overridden by the configuration file (summon.json in the
+// directory containing the executable, or the file named by SUMMON_CONFIG), which is in turn overridden by environment
+// variables. The given API key is the default key for OpenAI backends. If an OpenAI backend is selected but no API key
+// is available, the offline backend is used instead, and likewise the blocklist moderator is used instead of the OpenAI
+// moderator.
+func Load(apiKey string) (Config, error) {
+	config := Config{
+		Backend: Backend{
+			Type:   OpenAiBackend,
+			ApiKey: apiKey,
+		},
+		Health: Health{
+			Cooldown: Duration(10 * time.Minute),
+		},
+		Sampling: Sampling{
+			Temperature: 1,
+			MaxTokens:   600,
+		},
+		Summoning: Summoning{
+			MinDuration: Duration(8 * time.Second),
+			MaxDuration: Duration(26 * time.Second),
+			Candidates:  1,
+		},
+		Ritual: Ritual{
+			Length: StandardRitual,
+		},
+		Prompts: Prompts{
+			Timeout: Duration(4 * time.Second),
+		},
+		Conversation: Conversation{
+			Turns:   3,
+			Timeout: Duration(15 * time.Second),
+		},
+		Moderation: Moderation{
+			Type: BlocklistModerator,
+		},
+	}
+
+	if err := loadFile(&config); err != nil {
+		return Config{}, err
+	}
+	if err := loadEnv(&config); err != nil {
+		return Config{}, err
+	}
+
+	for _, backend := range append([]*Backend{&config.Backend}, pointersTo(config.Fallbacks)...) {
+		if backend.Type == OpenAiBackend && len(backend.ApiKey) == 0 {
+			backend.ApiKey = apiKey
+		}
+		if backend.Type == OpenAiBackend && (len(backend.ApiKey) == 0 || backend.ApiKey == UnsetApiKey) {
+			backend.Type = OfflineBackend
+		}
+	}
+	if config.Moderation.Type == OpenAiModerator && len(config.Moderation.ApiKey) == 0 &&
+		config.Backend.Type != OpenAiBackend {
+
+		config.Moderation.Type = BlocklistModerator
+	}
+	if config.Ritual.Length.Offerings() == 0 {
+		return Config{}, fmt.Errorf("unknown ritual length %q (must be %s, %s or %s)", config.Ritual.Length,
+			ShortRitual, StandardRitual, LongRitual)
+	}
+	config.Summoning.MinDuration = min(config.Summoning.MinDuration, config.Summoning.MaxDuration)
+	config.Summoning.Candidates = min(max(config.Summoning.Candidates, 1), maxCandidates)
+	config.Conversation.Turns = max(config.Conversation.Turns, 0)
+	if len(config.Usage.StatsFile) == 0 || len(config.Health.StateFile) == 0 || len(config.Prompts.StateFile) == 0 ||
+		len(config.Packs.Directory) == 0 {
+
+		directory, err := executableDirectory()
+		if err != nil {
+			return Config{}, err
+		}
+		if len(config.Usage.StatsFile) == 0 {
+			config.Usage.StatsFile = filepath.Join(directory, statsFilename)
+		}
+		if len(config.Health.StateFile) == 0 {
+			config.Health.StateFile = filepath.Join(directory, stateFilename)
+		}
+		if len(config.Prompts.StateFile) == 0 {
+			config.Prompts.StateFile = filepath.Join(directory, promptStateFilename)
+		}
+		if len(config.Packs.Directory) == 0 {
+			config.Packs.Directory = filepath.Join(directory, packsDirectoryName)
+		}
+	}
+
+	return config, nil
+}
+
+// loadFile reads the configuration file into the given configuration, if the file exists.
+func loadFile(config *Config) error {
+	path, ok := os.LookupEnv("SUMMON_CONFIG")
+	if !ok {
+		directory, err := executableDirectory()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(directory, configFilename)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return fmt.Errorf("invalid configuration file %q: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv reads any configuration set in environment variables into the given configuration.
+func loadEnv(config *Config) error {
+	setFromEnv("SUMMON_PERSONA", func(value string) { config.Persona = value })
+	setFromEnv("SUMMON_LOCALE", func(value string) { config.Locale = value })
+	setFromEnv("SUMMON_BACKEND", func(value string) { config.Backend.Type = BackendType(value) })
+	setFromEnv("SUMMON_API_KEY", func(value string) { config.Backend.ApiKey = value })
+	setFromEnv("SUMMON_BASE_URL", func(value string) { config.Backend.BaseUrl = value })
+	setFromEnv("SUMMON_MODEL", func(value string) { config.Backend.Model = value })
+	setFromEnv("SUMMON_RECORD", func(value string) { config.Backend.Record = value })
+	setFromEnv("SUMMON_REPLAY", func(value string) { config.Backend.Replay = value })
+	setFromEnv("SUMMON_MODERATION", func(value string) { config.Moderation.Type = ModeratorType(value) })
+	setFromEnv("SUMMON_STATS_FILE", func(value string) { config.Usage.StatsFile = value })
+	setFromEnv("SUMMON_STATE_FILE", func(value string) { config.Health.StateFile = value })
+	setFromEnv("SUMMON_PACKS_DIR", func(value string) { config.Packs.Directory = value })
+	setFromEnv("SUMMON_RITUAL_LENGTH", func(value string) { config.Ritual.Length = RitualLength(value) })
+	setFromEnv("SUMMON_PROMPT_STATE_FILE", func(value string) { config.Prompts.StateFile = value })
+
+	var errs []error
+	parseFromEnv := func(name string, parse func(value string) error) {
+		setFromEnv(name, func(value string) {
+			if err := parse(value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid value for %s: %w", name, err))
+			}
+		})
+	}
+	parseFromEnv("SUMMON_PRIVACY_MODE", func(value string) error {
+		privacyMode, err := strconv.ParseBool(value)
+		config.PrivacyMode = privacyMode
+		return err
+	})
+	parseFromEnv("SUMMON_SEED", func(value string) error {
+		seed, err := strconv.ParseUint(value, 10, 64)
+		config.Seed = seed
+		return err
+	})
+	parseFromEnv("SUMMON_FAMILY_FRIENDLY", func(value string) error {
+		familyFriendly, err := strconv.ParseBool(value)
+		config.Moderation.FamilyFriendly = familyFriendly
+		return err
+	})
+	parseFromEnv("SUMMON_TEMPERATURE", func(value string) error {
+		temperature, err := strconv.ParseFloat(value, 32)
+		config.Sampling.Temperature = float32(temperature)
+		return err
+	})
+	parseFromEnv("SUMMON_TOP_P", func(value string) error {
+		topP, err := strconv.ParseFloat(value, 32)
+		config.Sampling.TopP = float32(topP)
+		return err
+	})
+	parseFromEnv("SUMMON_MAX_TOKENS", func(value string) error {
+		maxTokens, err := strconv.Atoi(value)
+		config.Sampling.MaxTokens = maxTokens
+		return err
+	})
+	parseFromEnv("SUMMON_ADAPTIVE_PROMPTS", func(value string) error {
+		adaptive, err := strconv.ParseBool(value)
+		config.Prompts.Adaptive = adaptive
+		return err
+	})
+	parseFromEnv("SUMMON_PROMPT_TIMEOUT", func(value string) error {
+		timeout, err := time.ParseDuration(value)
+		config.Prompts.Timeout = Duration(timeout)
+		return err
+	})
+	parseFromEnv("SUMMON_MIN_SUMMONING_DURATION", func(value string) error {
+		duration, err := time.ParseDuration(value)
+		config.Summoning.MinDuration = Duration(duration)
+		return err
+	})
+	parseFromEnv("SUMMON_MAX_SUMMONING_DURATION", func(value string) error {
+		duration, err := time.ParseDuration(value)
+		config.Summoning.MaxDuration = Duration(duration)
+		return err
+	})
+	parseFromEnv("SUMMON_CANDIDATES", func(value string) error {
+		candidates, err := strconv.Atoi(value)
+		config.Summoning.Candidates = candidates
+		return err
+	})
+	parseFromEnv("SUMMON_CONVERSATION_TURNS", func(value string) error {
+		turns, err := strconv.Atoi(value)
+		config.Conversation.Turns = turns
+		return err
+	})
+	parseFromEnv("SUMMON_CONVERSATION_TIMEOUT", func(value string) error {
+		timeout, err := time.ParseDuration(value)
+		config.Conversation.Timeout = Duration(timeout)
+		return err
+	})
+	parseFromEnv("SUMMON_BACKEND_COOLDOWN", func(value string) error {
+		cooldown, err := time.ParseDuration(value)
+		config.Health.Cooldown = Duration(cooldown)
+		return err
+	})
+	parseFromEnv("SUMMON_BUDGET", func(value string) error {
+		budget, err := strconv.ParseFloat(value, 64)
+		config.Usage.Budget = budget
+		return err
+	})
+	parseFromEnv("SUMMON_SESSION_BUDGET", func(value string) error {
+		budget, err := strconv.ParseFloat(value, 64)
+		config.Usage.SessionBudget = budget
+		return err
+	})
+
+	return errors.Join(errs...)
+}
+
+// pointersTo returns pointers to each of the given backends.
+func pointersTo(backends []Backend) []*Backend {
+	pointers := make([]*Backend, len(backends))
+	for i := range backends {
+		pointers[i] = &backends[i]
+	}
+	return pointers
+}
+
+// executableDirectory returns the directory containing the game's executable.
+func executableDirectory() (string, error) {
+	pathToExecutable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(pathToExecutable), nil
+}
+
+// setFromEnv calls the given function with the value of the given environment variable, if it's set.
+func setFromEnv(name string, set func(value string)) {
+	if value, ok := os.LookupEnv(name); ok && len(value) > 0 {
+		set(value)
+	}
+}
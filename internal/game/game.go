@@ -2,59 +2,119 @@ package game
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/audio"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/config"
 	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/gen"
 	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/log"
 	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/messages"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/pii"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/random"
 	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/ui"
+	"math/rand/v2"
+	"strconv"
 	"time"
 )
 
 // Game executes the game logic. It implements tea.Model.
 type Game struct {
-	messageProvider   *messages.MessageProvider
-	creatureGenerator *gen.CreatureGenerator
-	currentState      gameState
-	uiBackground      ui.Background
-	uiMessages        []ui.Message
-	uiSummoningCircle ui.SummoningCircle
-	playerResponses   []string
+	messageProvider    *messages.MessageProvider
+	creatureGenerator  *gen.CreatureGenerator
+	currentState       gameState
+	uiBackground       ui.Background
+	uiMessages         []ui.Message
+	uiSummoningCircle  ui.SummoningCircle
+	prompts            []messages.Prompt
+	playerResponses    []string
+	creature           *gen.Creature
+	candidates         []gen.Creature
+	gameConfig         config.Config
+	summoningStartTime time.Time
+	descriptionUpdates chan descriptionUpdateMsg
+	descriptionId      int
+	conversation       []gen.ConversationTurn
+	conversationClosed bool
+	nextUiMessageId    int
+	randomSource       random.Source
+	messageRandom      *rand.Rand
 }
 
-// New creates a new Game.
-func New(messageProvider *messages.MessageProvider, creatureGenerator *gen.CreatureGenerator) *Game {
+// New creates a new Game, whose random choices are made using the given source.
+func New(messageProvider *messages.MessageProvider, creatureGenerator *gen.CreatureGenerator,
+	gameConfig config.Config, randomSource random.Source) *Game {
+
+	// If the player's answers could leave the machine, they're asked for their consent first.
+	initialState := introState
+	if !creatureGenerator.IsLocal() {
+		initialState = consentState
+	}
+
 	return &Game{
 		messageProvider:   messageProvider,
 		creatureGenerator: creatureGenerator,
+		currentState:      initialState,
+		gameConfig:        gameConfig,
+		randomSource:      randomSource,
+		messageRandom:     randomSource.Stream("messages"),
 	}
 }
 
+const (
+	// onlineConsentValue is the response given when the player consents to their answers being sent online.
+	onlineConsentValue = "online"
+
+	// offlineConsentValue is the response given when the player chooses to keep their answers on the machine.
+	offlineConsentValue = "offline"
+)
+
 // gameState represents the current state of the game.
 type gameState int
 
 const (
-	introState gameState = iota
+	consentState gameState = iota
+	introState
 	promptingState
 	summoningState
+	choosingState
+	conversingState
+	endingState
 )
 
-// addUiMessage adds a new message to the UI.
+// addUiMessage adds a new message to the UI. If replaceOlder is true, all messages except the most recent one are
+// removed first.
 type addUiMessageMsg struct {
-	uiMessage ui.Message
+	uiMessage    ui.Message
+	replaceOlder bool
 }
 
 // beginSummoning initializes the summoning circle.
 type beginSummoningMsg struct{}
 
+// descriptionUpdateMsg contains the creature description generated so far. If done is true, the description is
+// complete, and creature contains the generated creature (or nil if generation failed). If several candidate creatures
+// were generated for the player to choose between, candidates contains them instead, and there is no description.
+type descriptionUpdateMsg struct {
+	text       string
+	done       bool
+	creature   *gen.Creature
+	candidates []gen.Creature
+}
+
+// summoningMinDurationElapsedMsg indicates that the summoning has lasted its minimum duration, so the given update to
+// the creature description can be shown.
+type summoningMinDurationElapsedMsg struct {
+	update descriptionUpdateMsg
+}
+
 // exitGameMsg exits the game.
 type exitGameMsg struct{}
 
 // Init implements tea.Model by returning a tea.Cmd that updates the game state.
 func (g *Game) Init() tea.Cmd {
-	g.uiBackground = ui.NewBackground()
+	g.uiBackground = ui.NewBackground(g.randomSource.Stream("background"))
 	return tea.Batch(g.updateGameState, g.uiBackground.Init())
 }
 
@@ -72,22 +132,65 @@ func (g *Game) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		ui.UpdateTerminalSize(msg.Width, msg.Height)
 		// Don't return, since other components may need the window size message.
 	case addUiMessageMsg:
+		if msg.replaceOlder && len(g.uiMessages) > 1 {
+			g.uiMessages = g.uiMessages[len(g.uiMessages)-1:]
+		}
 		g.uiMessages = append(g.uiMessages, msg.uiMessage)
 		return g, msg.uiMessage.Init()
 	case ui.MessageResponseMsg:
+		if g.currentState == consentState {
+			g.acceptConsentResponse(msg.Response)
+			return g, g.updateGameState
+		}
+		if len(msg.Response) > 0 && gen.IsSuspiciousOffering(msg.Response) {
+			// Any personal information is redacted before the response is logged, so it never reaches the disk.
+			redactedResponse, _ := pii.Redact(msg.Response)
+			log.Logger.Print(fmt.Sprintf("func=\"game.Game.Update\", msg=\"Rejected suspicious response.\", "+
+				"response=\"%s\"", redactedResponse))
+			return g, g.rejectResponse(messages.OfferingRejectedMessage, messages.ConversationRejectedMessage)
+		}
+		if findings := pii.Detect(msg.Response); len(findings) > 0 {
+			// The response itself isn't logged, since it contains personal information.
+			log.Logger.Print(fmt.Sprintf("func=\"game.Game.Update\", msg=\"Rejected response containing personal "+
+				"information.\", kind=\"%s\"", findings[0].Kind))
+			return g, g.rejectResponse(messages.PersonalOfferingRejectedMessage, messages.PersonalWordsRejectedMessage)
+		}
 		if len(msg.Response) > 0 {
-			g.playerResponses = append(g.playerResponses, msg.Response)
+			switch g.currentState {
+			case promptingState:
+				g.playerResponses = append(g.playerResponses, msg.Response)
+			case choosingState:
+				g.chooseCandidate(msg.Response)
+			case conversingState:
+				g.conversation = append(g.conversation, gen.ConversationTurn{PlayerMessage: msg.Response})
+			}
 		}
 		return g, g.updateGameState
 	case beginSummoningMsg:
 		g.uiMessages = nil
 		g.uiSummoningCircle = ui.NewSummoningCircle(g.messageProvider.GetMessage(messages.SummoningMessage))
+		g.summoningStartTime = time.Now()
 
 		cmd := tea.Batch(
 			g.uiSummoningCircle.Init(),
 			g.performSummoning,
 		)
 		return g, cmd
+	case descriptionUpdateMsg:
+		if len(g.uiMessages) > 0 {
+			return g, g.forwardDescriptionUpdate(msg)
+		}
+
+		// This is the first update, so the summoning can end once it has lasted its minimum duration.
+		remainingDuration := time.Duration(g.gameConfig.Summoning.MinDuration) - time.Since(g.summoningStartTime)
+		if remainingDuration > 0 {
+			return g, tea.Tick(remainingDuration, func(t time.Time) tea.Msg {
+				return summoningMinDurationElapsedMsg{update: msg}
+			})
+		}
+		return g, g.endSummoning(msg)
+	case summoningMinDurationElapsedMsg:
+		return g, g.endSummoning(msg.update)
 	case exitGameMsg:
 		return g, tea.Quit
 	}
@@ -132,6 +235,8 @@ func (g *Game) View() string {
 // updateGameState advances the game state.
 func (g *Game) updateGameState() tea.Msg {
 	switch g.currentState {
+	case consentState:
+		return g.addNewUiConsentMessage()
 	case introState:
 		switch len(g.uiMessages) {
 		case 0:
@@ -143,55 +248,379 @@ func (g *Game) updateGameState() tea.Msg {
 			return g.addNewUiPrompt()
 		}
 	case promptingState:
-		if len(g.playerResponses) < 5 {
+		if len(g.playerResponses) < g.gameConfig.Ritual.Length.Offerings() {
 			return g.addNewUiPrompt()
 		} else {
 			g.currentState = summoningState
 			return beginSummoningMsg{}
 		}
 	case summoningState:
-		if len(g.uiMessages) > 1 {
-			return exitGameMsg{}
+		// The creature description has been acknowledged. If no creature appeared, there's nothing to talk to.
+		if g.creature != nil && g.gameConfig.Conversation.Turns > 0 {
+			g.currentState = conversingState
+			return g.addNewUiConversationMessage(g.messageProvider.GetMessage(messages.ConversationBeginMessage),
+				true)
 		}
+		g.currentState = endingState
 		return g.addNewUiMessage(g.messageProvider.GetMessage(messages.EndingMessage))
+	case choosingState:
+		if g.creature == nil {
+			return g.addNewUiCandidatesMessage()
+		}
+
+		// A creature has been chosen, so it comes through the circle.
+		g.currentState = summoningState
+		return g.addNewUiDescriptionMessage(*g.creature)
+	case conversingState:
+		return g.continueConversation()
+	case endingState:
+		return exitGameMsg{}
 	}
 
 	return nil
 }
 
-// performSummoning performs the summoning logic and generates the creature description.
-func (g *Game) performSummoning() tea.Msg {
-	var description string
+// acceptConsentResponse applies the player's response to the consent screen, then clears the screen for the intro.
+func (g *Game) acceptConsentResponse(response string) {
+	log.Logger.Print(fmt.Sprintf("func=\"game.Game.acceptConsentResponse\", msg=\"Consent given.\", "+
+		"response=\"%s\"", response))
+	g.creatureGenerator.SetLocalOnly(response != onlineConsentValue)
+	g.uiMessages = nil
+	g.currentState = introState
+}
+
+// chooseCandidate makes the candidate creature with the given index the creature that comes through the circle. The
+// rejected candidates are recorded in the log as the forms that almost were.
+func (g *Game) chooseCandidate(response string) {
+	index, err := strconv.Atoi(response)
+	if err != nil || index < 0 || index >= len(g.candidates) {
+		index = 0
+	}
+	g.creature = &g.candidates[index]
+
+	for i, candidate := range g.candidates {
+		if i != index {
+			log.Logger.Print(fmt.Sprintf("func=\"game.Game.chooseCandidate\", msg=\"A form that almost was.\", "+
+				"name=\"%s\", epithet=\"%s\", description=\"%s\"", candidate.Name, candidate.Epithet,
+				candidate.Render()))
+		}
+	}
+}
+
+// continueConversation advances the conversation with the creature. Each message from the player gets a reply, until
+// the turn limit is reached, after which the creature has the last word.
+func (g *Game) continueConversation() tea.Msg {
+	if g.conversationClosed {
+		g.currentState = endingState
+		return g.addNewUiMessage(g.messageProvider.GetMessage(messages.EndingMessage))
+	}
+
+	lastTurn := &g.conversation[len(g.conversation)-1]
+	if len(lastTurn.CreatureReply) > 0 {
+		// The creature's last reply has been acknowledged, so the conversation is over.
+		g.conversationClosed = true
+		return g.addNewUiConversationMessage(g.messageProvider.GetMessage(messages.ConversationClosingMessage),
+			false)
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(g.gameConfig.Conversation.Timeout))
 	defer cancel()
+	reply, err := g.creatureGenerator.GenerateReply(ctx, *g.creature, g.offerings(), g.conversation)
+	if err != nil {
+		log.Logger.Print(fmt.Sprintf("func=\"game.Game.continueConversation\", msg=\"Reply failed.\", "+
+			"error=\"%v\"", err))
+		g.conversationClosed = true
+		return g.addNewUiConversationMessage(g.messageProvider.GetMessage(messages.ConversationClosingMessage),
+			false)
+	}
+	lastTurn.CreatureReply = reply
+
+	return g.addNewUiConversationMessage(reply, len(g.conversation) < g.gameConfig.Conversation.Turns)
+}
+
+// descriptionStreamTimeout is how long the creature description may keep streaming in after the summoning has ended.
+const descriptionStreamTimeout = 30 * time.Second
+
+// summoningSoundFadeOutDuration is how long it takes the summoning sound effect to fade out once the summoning ends.
+const summoningSoundFadeOutDuration = 2 * time.Second
+
+// performSummoning starts the summoning sound effect and starts generating the creature description in the background,
+// then waits for the first update to the description. Updates are sent to the UI through descriptionUpdateMsg.
+func (g *Game) performSummoning() tea.Msg {
+	// Intermediate updates may be dropped if the UI falls behind, since each update contains the full text so far. The
+	// final update is always delivered.
+	updates := make(chan descriptionUpdateMsg, 16)
+	g.descriptionUpdates = updates
+	offerings := g.offerings()
+
 	go func() {
-		description = g.creatureGenerator.GenerateDescription(ctx, g.playerResponses)
+		defer close(updates)
+
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+		maxDuration := time.Duration(g.gameConfig.Summoning.MaxDuration)
+		ctx, cancelTimeout := context.WithTimeout(ctx, maxDuration+descriptionStreamTimeout)
+		defer cancelTimeout()
+
+		// If nothing has arrived by the end of the summoning, stop waiting on the backend, so the creature generator
+		// can fall back to generating the creature offline.
+		firstUpdateTimer := time.AfterFunc(maxDuration, func() { cancel(gen.ErrTimeout) })
+		defer firstUpdateTimer.Stop()
+
+		var creatures []gen.Creature
+		var err error
+		if candidateCount := g.gameConfig.Summoning.Candidates; candidateCount > 1 {
+			creatures, err = g.creatureGenerator.GenerateCandidates(ctx, offerings, candidateCount)
+		} else {
+			var creature gen.Creature
+			creature, err = g.creatureGenerator.GenerateCreature(ctx, offerings, func(text string) {
+				firstUpdateTimer.Stop()
+				select {
+				case updates <- descriptionUpdateMsg{text: text}:
+				default:
+				}
+			})
+			creatures = []gen.Creature{creature}
+		}
+		if err != nil {
+			log.Logger.Print(fmt.Sprintf("func=\"game.Game.performSummoning\", msg=\"Summoning failed.\", "+
+				"error=\"%v\"", err))
+			updates <- descriptionUpdateMsg{
+				text: g.messageProvider.GetMessage(summoningErrorMessageKey(err)),
+				done: true,
+			}
+			return
+		}
+		if len(creatures) > 1 {
+			updates <- descriptionUpdateMsg{done: true, candidates: creatures}
+			return
+		}
+		updates <- descriptionUpdateMsg{text: creatures[0].Render(), done: true, creature: &creatures[0]}
 	}()
 
-	_ = audio.Play(audio.DialupModemSoundEffect, nil, false)
-	time.Sleep(26 * time.Second)
+	// The summoning sound effect loops until the summoning ends, in case the creature takes longer than the sound.
+	_ = audio.PlayLooping(audio.DialupModemSoundEffect)
+
+	return g.waitForDescriptionUpdate()
+}
+
+// endSummoning ends the summoning with the given first update. If the update contains several candidate creatures,
+// the player chooses between them. Otherwise, the creature description begins.
+func (g *Game) endSummoning(update descriptionUpdateMsg) tea.Cmd {
+	audio.FadeOut(audio.DialupModemSoundEffect, summoningSoundFadeOutDuration)
+
+	if len(update.candidates) > 0 {
+		g.candidates = update.candidates
+		g.currentState = choosingState
+		return g.updateGameState
+	}
+	return g.beginDescription(update)
+}
 
-	cancel()
-	if len(description) == 0 {
-		description = g.messageProvider.GetMessage(messages.SummoningErrorMessage)
+// beginDescription adds the streaming message that displays the creature description, starting with the given update.
+func (g *Game) beginDescription(update descriptionUpdateMsg) tea.Cmd {
+	g.descriptionId = g.newUiMessageId()
+	uiPlaceholder := ui.NewPlaceholder(g.messageProvider.GetMessage(messages.AwaitingAcknowledgementMessage))
+	uiMessage := ui.NewStreamingMessage(g.descriptionId, uiPlaceholder, g.messageRandom).
+		WithNotesHint(g.messageProvider.GetMessage(messages.AwaitingAcknowledgementWithNotesMessage))
+	g.uiMessages = append(g.uiMessages, uiMessage)
+	return tea.Batch(uiMessage.Init(), g.forwardDescriptionUpdate(update))
+}
+
+// forwardDescriptionUpdate forwards the given update to the message that displays the creature description, then
+// waits for the next update if there is one.
+func (g *Game) forwardDescriptionUpdate(update descriptionUpdateMsg) tea.Cmd {
+	if update.done {
+		g.creature = update.creature
 	}
 
-	return g.addNewUiMessage(description)
+	streamMsg := ui.MessageStreamMsg{Id: g.descriptionId, Text: update.text, Done: update.done}
+	if update.creature != nil {
+		streamMsg.Highlights = g.attributionHighlights(*update.creature)
+	}
+	cmd := func() tea.Msg { return streamMsg }
+	if !update.done {
+		cmd = tea.Batch(cmd, g.waitForDescriptionUpdate)
+	}
+	return cmd
+}
+
+// summoningErrorMessageKey returns the key of the message to show when the summoning fails with the given error.
+func summoningErrorMessageKey(err error) messages.MessageKey {
+	switch {
+	case errors.Is(err, gen.ErrAuthentication):
+		return messages.SummoningAuthenticationErrorMessage
+	case errors.Is(err, gen.ErrModerated):
+		return messages.SummoningModeratedMessage
+	case errors.Is(err, gen.ErrTimeout):
+		return messages.SummoningTimeoutErrorMessage
+	case errors.Is(err, gen.ErrUnavailable) || errors.Is(err, gen.ErrRateLimited):
+		return messages.SummoningOutageErrorMessage
+	default:
+		return messages.SummoningErrorMessage
+	}
+}
+
+// waitForDescriptionUpdate waits for the next update to the creature description.
+func (g *Game) waitForDescriptionUpdate() tea.Msg {
+	update, ok := <-g.descriptionUpdates
+	if !ok {
+		return nil
+	}
+	return update
 }
 
 // addNewUiMessage adds a new message to the UI.
 func (g *Game) addNewUiMessage(text string) tea.Msg {
-	id := len(g.uiMessages)
+	id := g.newUiMessageId()
 	uiPlaceholder := ui.NewPlaceholder(g.messageProvider.GetMessage(messages.AwaitingAcknowledgementMessage))
-	uiMessage := ui.NewMessage(id, text, uiPlaceholder)
+	uiMessage := ui.NewMessage(id, text, uiPlaceholder, g.messageRandom)
+	return addUiMessageMsg{uiMessage: uiMessage}
+}
+
+// addNewUiDescriptionMessage adds a message describing the given creature to the UI, with the parts that came from
+// the player's responses highlighted.
+func (g *Game) addNewUiDescriptionMessage(creature gen.Creature) tea.Msg {
+	id := g.newUiMessageId()
+	uiPlaceholder := ui.NewPlaceholder(g.messageProvider.GetMessage(messages.AwaitingAcknowledgementMessage))
+	uiMessage := ui.NewMessage(id, creature.Render(), uiPlaceholder, g.messageRandom).
+		WithHighlights(g.attributionHighlights(creature)).
+		WithNotesHint(g.messageProvider.GetMessage(messages.AwaitingAcknowledgementWithNotesMessage))
+	return addUiMessageMsg{uiMessage: uiMessage}
+}
+
+// attributionHighlights returns highlights for the parts of the given creature's description that came from the
+// player's responses. The note of each highlight is the response it came from.
+func (g *Game) attributionHighlights(creature gen.Creature) []ui.Highlight {
+	var highlights []ui.Highlight
+	for _, span := range creature.AttributionSpans() {
+		if span.Answer > len(g.playerResponses) {
+			continue
+		}
+		highlights = append(highlights, ui.Highlight{
+			Start: span.Start,
+			End:   span.End,
+			Note:  "\"" + g.playerResponses[span.Answer-1] + "\"",
+		})
+	}
+	return highlights
+}
+
+// addNewUiConsentMessage adds the message asking the player whether their answers may be sent online to the UI.
+func (g *Game) addNewUiConsentMessage() tea.Msg {
+	id := g.newUiMessageId()
+	uiChoice := ui.NewChoice(id,
+		ui.ChoiceOption{
+			Value: onlineConsentValue,
+			Label: g.messageProvider.GetMessage(messages.ConsentOnlineOption),
+		},
+		ui.ChoiceOption{
+			Value: offlineConsentValue,
+			Label: g.messageProvider.GetMessage(messages.ConsentOfflineOption),
+		},
+	)
+	uiMessage := ui.NewMessage(id, g.messageProvider.GetMessage(messages.ConsentMessage), uiChoice,
+		g.messageRandom)
+	return addUiMessageMsg{uiMessage: uiMessage}
+}
+
+// addNewUiCandidatesMessage adds the message asking the player to choose between the candidate creatures to the UI.
+func (g *Game) addNewUiCandidatesMessage() tea.Msg {
+	options := make([]ui.ChoiceOption, len(g.candidates))
+	for i, candidate := range g.candidates {
+		label := candidate.Name
+		if len(candidate.Epithet) > 0 {
+			label += ", " + candidate.Epithet
+		}
+		options[i] = ui.ChoiceOption{Value: strconv.Itoa(i), Label: label}
+	}
+
+	id := g.newUiMessageId()
+	uiChoice := ui.NewChoice(id, options...)
+	uiMessage := ui.NewMessage(id, g.messageProvider.GetMessage(messages.SummoningCandidatesMessage), uiChoice,
+		g.messageRandom)
 	return addUiMessageMsg{uiMessage: uiMessage}
 }
 
 // addNewUiPrompt adds a new prompt to the UI.
 func (g *Game) addNewUiPrompt() tea.Msg {
-	id := len(g.uiMessages)
+	prompt, err := g.nextPrompt()
+	if err != nil {
+		// There's nothing left to ask, so the ritual ends early.
+		log.Logger.Print(fmt.Sprintf("func=\"game.Game.addNewUiPrompt\", msg=\"No prompt available, so the "+
+			"ritual ends early.\", offerings=\"%d\", error=\"%v\"", len(g.playerResponses), err))
+		g.currentState = summoningState
+		return beginSummoningMsg{}
+	}
+	g.prompts = append(g.prompts, prompt)
+
+	id := g.newUiMessageId()
 	uiInput := ui.NewInput(id)
-	uiMessage := ui.NewMessage(id, g.messageProvider.GetPrompt(), uiInput)
+	uiMessage := ui.NewMessage(id, prompt.Text, uiInput, g.messageRandom)
 	return addUiMessageMsg{uiMessage: uiMessage}
 }
+
+// addNewUiConversationMessage adds a new message from the conversation with the creature to the UI, replacing all but
+// the previous message. If awaitingReply is true, the player can respond to it.
+func (g *Game) addNewUiConversationMessage(text string, awaitingReply bool) tea.Msg {
+	id := g.newUiMessageId()
+	var uiMessage ui.Message
+	if awaitingReply {
+		uiMessage = ui.NewMessage(id, text, ui.NewInput(id), g.messageRandom)
+	} else {
+		uiPlaceholder := ui.NewPlaceholder(g.messageProvider.GetMessage(messages.AwaitingAcknowledgementMessage))
+		uiMessage = ui.NewMessage(id, text, uiPlaceholder, g.messageRandom)
+	}
+	return addUiMessageMsg{uiMessage: uiMessage, replaceOlder: true}
+}
+
+// rejectResponse returns a tea.Cmd that asks the player to respond again, after the ritual rejected their response.
+// The message with the first given key is shown during the ritual, and the message with the second given key is shown
+// during the conversation with the creature.
+func (g *Game) rejectResponse(offeringRejectedKey, wordsRejectedKey messages.MessageKey) tea.Cmd {
+	return func() tea.Msg {
+		id := g.newUiMessageId()
+		if g.currentState == conversingState {
+			text := g.messageProvider.GetMessage(wordsRejectedKey)
+			uiMessage := ui.NewMessage(id, text, ui.NewInput(id), g.messageRandom)
+			return addUiMessageMsg{uiMessage: uiMessage, replaceOlder: true}
+		}
+
+		text := g.messageProvider.GetMessage(offeringRejectedKey) + " " + g.prompts[len(g.prompts)-1].Text
+		return addUiMessageMsg{uiMessage: ui.NewMessage(id, text, ui.NewInput(id), g.messageRandom)}
+	}
+}
+
+// newUiMessageId returns a new ID for a message in the UI. IDs are never reused, so messages that are still animating
+// when they're removed can't interfere with new ones.
+func (g *Game) newUiMessageId() int {
+	id := g.nextUiMessageId
+	g.nextUiMessageId++
+	return id
+}
+
+// offerings returns the prompts of the ritual paired with the player's responses to them.
+func (g *Game) offerings() []gen.Offering {
+	offerings := make([]gen.Offering, len(g.playerResponses))
+	for i, response := range g.playerResponses {
+		offerings[i] = gen.Offering{Prompt: g.prompts[i].Text, Response: response, Category: g.prompts[i].Category}
+	}
+	return offerings
+}
+
+// nextPrompt returns the next prompt of the ritual. If adaptive prompts are enabled, prompts after the first are
+// generated from the player's previous responses, falling back to a static prompt if generation fails or runs slow. If
+// there are no static prompts left, messages.ErrNoPrompts is returned. The category of a generated prompt isn't known.
+func (g *Game) nextPrompt() (messages.Prompt, error) {
+	if !g.gameConfig.Prompts.Adaptive || len(g.playerResponses) == 0 {
+		return g.messageProvider.GetPrompt()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(g.gameConfig.Prompts.Timeout))
+	defer cancel()
+	prompt, err := g.creatureGenerator.GeneratePrompt(ctx, g.offerings())
+	if err != nil {
+		return g.messageProvider.GetPrompt()
+	}
+	return messages.Prompt{Text: prompt}, nil
+}
@@ -0,0 +1,150 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/config"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/messages"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/usage"
+	"net/http"
+)
+
+// DescriptionBackend produces the text completions used by the CreatureGenerator.
+type DescriptionBackend interface {
+	// Name returns a short name that identifies the backend in the log. Backends of the same type that use different
+	// models or servers have different names, since their health is tracked by name.
+	Name() string
+
+	// Local returns whether the backend runs entirely on this machine, without opening any network connections.
+	Local() bool
+
+	// Complete returns a completion for the given request.
+	Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error)
+}
+
+// StreamingBackend is a DescriptionBackend that can also stream its completions as they are generated.
+type StreamingBackend interface {
+	DescriptionBackend
+
+	// CompleteStream returns a completion for the given request, calling onUpdate with the full text received so far
+	// each time more of the completion arrives.
+	CompleteStream(ctx context.Context, request CompletionRequest, onUpdate func(text string)) (CompletionResponse,
+		error)
+}
+
+// Role is the role of the author of a CompletionMessage.
+type Role string
+
+const (
+	SystemRole    Role = "system"
+	UserRole      Role = "user"
+	AssistantRole Role = "assistant"
+)
+
+// CompletionMessage is a single message in a CompletionRequest.
+type CompletionMessage struct {
+	Role    Role
+	Content string
+}
+
+// Task identifies what a CompletionRequest is for.
+type Task int
+
+const (
+	DescriptionTask Task = iota
+	PromptTask
+	ReplyTask
+)
+
+// String returns the name of the task, such as "description".
+func (t Task) String() string {
+	switch t {
+	case DescriptionTask:
+		return "description"
+	case PromptTask:
+		return "prompt"
+	case ReplyTask:
+		return "reply"
+	default:
+		return fmt.Sprintf("task(%d)", int(t))
+	}
+}
+
+// CompletionRequest is a request for a completion from a DescriptionBackend.
+type CompletionRequest struct {
+	Task     Task
+	Messages []CompletionMessage
+
+	// Attributes are the player's responses that the messages were built from, in the order they were given. They're
+	// used by backends that generate text without a language model, and to tell recorded responses apart.
+	Attributes []string
+
+	// Categories are the kinds of attribute the player was asked for, such as colors or sounds, in the same order as
+	// Attributes. A category is empty if it isn't known, and Categories may be nil if no categories are known.
+	Categories []messages.Category
+
+	// Language is the language the player is using, as a language tag such as "es". It's used by backends that
+	// generate text without a language model, which fall back to English for languages they don't support.
+	Language string
+
+	// Variant distinguishes requests that are otherwise the same, such as candidate creatures generated at once.
+	// Backends that generate text without a language model use it to vary what they generate.
+	Variant int
+
+	// JsonOutput indicates that the completion must be a JSON object.
+	JsonOutput bool
+
+	// Sampling contains the sampling parameters for backends that use a language model.
+	Sampling config.Sampling
+}
+
+// CompletionResponse is the response to a CompletionRequest.
+type CompletionResponse struct {
+	Content string
+
+	// Model is the name of the model that generated the completion, as reported by the backend.
+	Model string
+
+	// Usage is the number of tokens the completion used, as reported by the backend. It's zero for backends that
+	// don't use a language model.
+	Usage usage.Usage
+}
+
+// NewBackend creates the DescriptionBackend described by the given configuration. If a cassette file to replay is
+// given, a ReplayBackend is created regardless of the backend's type. If a cassette file to record to is given, the
+// backend's HTTP exchanges are recorded to it.
+func NewBackend(backendConfig config.Backend) (DescriptionBackend, error) {
+	if len(backendConfig.Replay) > 0 {
+		if len(backendConfig.Record) > 0 {
+			return nil, errors.New("a backend can't record and replay at the same time")
+		}
+		return NewReplayBackend(backendConfig.Replay, backendConfig.Model)
+	}
+
+	var transport http.RoundTripper
+	if len(backendConfig.Record) > 0 {
+		if backendConfig.Type != config.OpenAiBackend && backendConfig.Type != config.OpenAiCompatibleBackend {
+			return nil, fmt.Errorf("recording isn't supported by the %s backend", backendConfig.Type)
+		}
+		cassette, err := NewRecordingCassette(backendConfig.Record)
+		if err != nil {
+			return nil, err
+		}
+		transport = cassette
+	}
+
+	switch backendConfig.Type {
+	case config.OpenAiBackend:
+		return NewOpenAiBackend(backendConfig.ApiKey, backendConfig.Model, transport), nil
+	case config.OpenAiCompatibleBackend:
+		return NewOpenAiCompatibleBackend(backendConfig.BaseUrl, backendConfig.ApiKey, backendConfig.Model,
+			transport)
+	case config.OfflineBackend:
+		return NewOfflineBackend(), nil
+	case config.FakeBackend:
+		return NewFakeBackend(), nil
+	default:
+		return nil, fmt.Errorf("unknown backend type %q", backendConfig.Type)
+	}
+}
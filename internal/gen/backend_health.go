@@ -0,0 +1,119 @@
+package gen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/config"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/log"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// backendHealth remembers which backends have recently failed, so they can be skipped until their cooldown is over.
+// Failures are saved to a state file, so they're remembered across sessions. Local backends never fail in a way that
+// waiting would help, so they're always considered healthy. It's safe for concurrent use.
+type backendHealth struct {
+	mutex    sync.Mutex
+	path     string
+	cooldown time.Duration
+	failures map[string]time.Time
+}
+
+// backendHealthState is the contents of the backend health state file.
+type backendHealthState struct {
+	// Failures are the times that backends last failed, keyed by the backends' names, which distinguish backends of the
+	// same type with different models or servers.
+	Failures map[string]time.Time `json:"failures"`
+}
+
+// newBackendHealth creates a new backendHealth described by the given configuration. The state file is only a cache,
+// so if it can't be read, the failures in it are forgotten.
+func newBackendHealth(healthConfig config.Health) *backendHealth {
+	health := &backendHealth{
+		path:     healthConfig.StateFile,
+		cooldown: time.Duration(healthConfig.Cooldown),
+		failures: make(map[string]time.Time),
+	}
+	if len(health.path) == 0 {
+		return health
+	}
+
+	var state backendHealthState
+	data, err := os.ReadFile(health.path)
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Logger.Print(fmt.Sprintf("func=\"gen.newBackendHealth\", msg=\"Failed to read backend health state "+
+			"file.\", path=\"%s\", error=\"%v\"", health.path, err))
+	}
+	for name, failedAt := range state.Failures {
+		health.failures[name] = failedAt
+	}
+	return health
+}
+
+// isHealthy returns whether the given backend hasn't failed within its cooldown.
+func (h *backendHealth) isHealthy(backend DescriptionBackend) bool {
+	if backend.Local() {
+		return true
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	failedAt, failed := h.failures[backend.Name()]
+	return !failed || time.Since(failedAt) >= h.cooldown
+}
+
+// recordFailure remembers that the given backend failed with the given error, so it's skipped until its cooldown is
+// over. Only errors that mean the backend is unavailable are remembered. A backend that responded with something
+// unusable, such as invalid JSON or flagged text, may well do better next time, so it isn't skipped.
+func (h *backendHealth) recordFailure(backend DescriptionBackend, err error) {
+	if backend.Local() || !isAvailabilityError(err) {
+		return
+	}
+
+	log.Logger.Print(fmt.Sprintf("func=\"gen.backendHealth.recordFailure\", msg=\"Backend will be skipped until its "+
+		"cooldown is over.\", backend=\"%s\", cooldown=\"%s\", error=\"%v\"", backend.Name(), h.cooldown, err))
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.failures[backend.Name()] = time.Now()
+	h.save()
+}
+
+// recordSuccess forgets any failure of the given backend.
+func (h *backendHealth) recordSuccess(backend DescriptionBackend) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, failed := h.failures[backend.Name()]; failed {
+		delete(h.failures, backend.Name())
+		h.save()
+	}
+}
+
+// save writes the failures that are still within their cooldown to the state file. The mutex must be held.
+func (h *backendHealth) save() {
+	if len(h.path) == 0 {
+		return
+	}
+
+	state := backendHealthState{Failures: make(map[string]time.Time)}
+	for name, failedAt := range h.failures {
+		if time.Since(failedAt) < h.cooldown {
+			state.Failures[name] = failedAt
+		}
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		err = os.WriteFile(h.path, data, 0644)
+	}
+	if err != nil {
+		log.Logger.Print(fmt.Sprintf("func=\"gen.backendHealth.save\", msg=\"Failed to write backend health state "+
+			"file.\", path=\"%s\", error=\"%v\"", h.path, err))
+	}
+}
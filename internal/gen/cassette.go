@@ -0,0 +1,248 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/log"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// cassetteKeyContextKey is the context key under which the cassette key of a completion request is stored.
+type cassetteKeyContextKey struct{}
+
+// cassetteKey identifies the recorded responses to a completion request, by its task, the player's answers it was
+// built from, and its variant. The rest of the request isn't part of the key, since it includes randomly chosen
+// prompts.
+type cassetteKey struct {
+	Task    string   `json:"task"`
+	Answers []string `json:"answers"`
+	Variant int      `json:"variant,omitempty"`
+}
+
+// withCassetteKey returns a copy of the given context that carries the cassette key of the given request, so that a
+// Cassette can tell which recording the HTTP requests made with the context belong to.
+func withCassetteKey(ctx context.Context, request CompletionRequest) context.Context {
+	return context.WithValue(ctx, cassetteKeyContextKey{}, cassetteKey{
+		Task:    request.Task.String(),
+		Answers: request.Attributes,
+		Variant: request.Variant,
+	})
+}
+
+// equal returns whether the key is the same as the given key.
+func (k cassetteKey) equal(other cassetteKey) bool {
+	return k.Task == other.Task && slices.Equal(k.Answers, other.Answers) && k.Variant == other.Variant
+}
+
+// cassetteEntry is a single HTTP exchange recorded in a cassette file.
+type cassetteEntry struct {
+	Key cassetteKey `json:"key"`
+
+	// Request is the body of the request. It's only recorded for reference, and isn't used when replaying.
+	Request json.RawMessage `json:"request,omitempty"`
+
+	StatusCode  int    `json:"statusCode"`
+	ContentType string `json:"contentType"`
+	Body        string `json:"body"`
+}
+
+// Cassette is an http.RoundTripper that either records HTTP exchanges to a cassette file, or replays them from one
+// without opening any network connections. Exchanges are keyed by the cassette key of the request's context (see
+// withCassetteKey). When the same key is requested more than once, the responses recorded for it are replayed in the
+// order they were recorded, and the last one is repeated once they run out, so a replay is always deterministic.
+type Cassette struct {
+	path      string
+	replaying bool
+	mutex     sync.Mutex
+	entries   []cassetteEntry
+	replayed  map[string]int
+}
+
+// NewRecordingCassette creates a Cassette that sends requests over the network and records each exchange to the
+// cassette file at the given path. If the file already exists, new exchanges are added to those already in it.
+func NewRecordingCassette(path string) (*Cassette, error) {
+	entries, err := readCassetteFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return &Cassette{path: path, entries: entries}, nil
+}
+
+// NewReplayingCassette creates a Cassette that replays the exchanges recorded in the cassette file at the given path.
+// Requests with no recorded response receive a "not found" error response.
+func NewReplayingCassette(path string) (*Cassette, error) {
+	entries, err := readCassetteFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Cassette{path: path, replaying: true, entries: entries, replayed: make(map[string]int)}, nil
+}
+
+// RoundTrip implements http.RoundTripper by replaying or recording the exchange for the given request.
+func (c *Cassette) RoundTrip(request *http.Request) (*http.Response, error) {
+	key, _ := request.Context().Value(cassetteKeyContextKey{}).(cassetteKey)
+
+	var requestBody []byte
+	if request.Body != nil {
+		var err error
+		requestBody, err = io.ReadAll(request.Body)
+		_ = request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.replaying {
+		return c.replay(request, key), nil
+	}
+	return c.record(request, key, requestBody)
+}
+
+// replay returns the recorded response for the given request and key.
+func (c *Cassette) replay(request *http.Request, key cassetteKey) *http.Response {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var matches []cassetteEntry
+	for _, entry := range c.entries {
+		if entry.Key.equal(key) {
+			matches = append(matches, entry)
+		}
+	}
+	if len(matches) == 0 {
+		log.Logger.Print(fmt.Sprintf("func=\"gen.Cassette.replay\", msg=\"No response was recorded for the "+
+			"request.\", path=\"%s\", task=\"%s\"", c.path, key.Task))
+		return newCassetteResponse(request, http.StatusNotFound, "application/json",
+			`{"error":{"message":"no response was recorded for this request","type":"invalid_request_error"}}`)
+	}
+
+	keyJson, _ := json.Marshal(key)
+	index := min(c.replayed[string(keyJson)], len(matches)-1)
+	c.replayed[string(keyJson)]++
+
+	entry := matches[index]
+	return newCassetteResponse(request, entry.StatusCode, entry.ContentType, entry.Body)
+}
+
+// record sends the given request over the network, and records the exchange once the response body has been read.
+// Exchanges whose response body can't be read in full, such as a stream that is canceled, aren't recorded.
+func (c *Cassette) record(request *http.Request, key cassetteKey, requestBody []byte) (*http.Response, error) {
+	outgoingRequest := request.Clone(request.Context())
+	if requestBody != nil {
+		outgoingRequest.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+
+	response, err := http.DefaultTransport.RoundTrip(outgoingRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := cassetteEntry{
+		Key:         key,
+		StatusCode:  response.StatusCode,
+		ContentType: response.Header.Get("Content-Type"),
+	}
+	if json.Valid(requestBody) {
+		entry.Request = requestBody
+	}
+	response.Body = &recordingBody{
+		ReadCloser: response.Body,
+		onComplete: func(body []byte) {
+			entry.Body = string(body)
+			c.save(entry)
+		},
+	}
+	return response, nil
+}
+
+// save adds the given entry to the cassette and writes the cassette file.
+func (c *Cassette) save(entry cassetteEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = append(c.entries, entry)
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err == nil {
+		err = os.WriteFile(c.path, data, 0644)
+	}
+	if err != nil {
+		log.Logger.Print(fmt.Sprintf("func=\"gen.Cassette.save\", msg=\"Failed to write cassette file.\", "+
+			"path=\"%s\", error=\"%v\"", c.path, err))
+	}
+}
+
+// readCassetteFile returns the entries in the cassette file at the given path.
+func readCassetteFile(path string) ([]cassetteEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []cassetteEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid cassette file %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// newCassetteResponse returns a response to the given request with the given status code, content type and body.
+func newCassetteResponse(request *http.Request, statusCode int, contentType, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{contentType}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}
+}
+
+// recordingBody is a response body that keeps a copy of everything read from it, and calls onComplete with the full
+// body once it has been read to the end. If it's closed before then, the rest of the body is read first, so responses
+// that are decoded without reading to the end are still recorded.
+type recordingBody struct {
+	io.ReadCloser
+	buffer     bytes.Buffer
+	onComplete func(body []byte)
+	completed  bool
+}
+
+// Read implements io.Reader by reading from the underlying body and keeping a copy of what was read.
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buffer.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		b.complete()
+	}
+	return n, err
+}
+
+// Close implements io.Closer by reading the rest of the underlying body, and then closing it.
+func (b *recordingBody) Close() error {
+	if !b.completed {
+		if _, err := io.Copy(&b.buffer, b.ReadCloser); err == nil {
+			b.complete()
+		}
+	}
+	return b.ReadCloser.Close()
+}
+
+// complete calls onComplete with the full body, unless it has already been called.
+func (b *recordingBody) complete() {
+	if !b.completed {
+		b.completed = true
+		b.onComplete(b.buffer.Bytes())
+	}
+}
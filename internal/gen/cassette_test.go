@@ -0,0 +1,172 @@
+package gen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/config"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/messages"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCassetteKeyEqual(t *testing.T) {
+	key := cassetteKey{Task: "description", Answers: []string{"red", "velvet"}, Variant: 1}
+	tests := []struct {
+		name  string
+		other cassetteKey
+		equal bool
+	}{
+		{"same", cassetteKey{Task: "description", Answers: []string{"red", "velvet"}, Variant: 1}, true},
+		{"different task", cassetteKey{Task: "reply", Answers: []string{"red", "velvet"}, Variant: 1}, false},
+		{"different answers", cassetteKey{Task: "description", Answers: []string{"red"}, Variant: 1}, false},
+		{"answers in a different order", cassetteKey{Task: "description", Answers: []string{"velvet", "red"},
+			Variant: 1}, false},
+		{"different variant", cassetteKey{Task: "description", Answers: []string{"red", "velvet"}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := key.equal(test.other); got != test.equal {
+				t.Errorf("%+v.equal(%+v) = %t, want %t", key, test.other, got, test.equal)
+			}
+		})
+	}
+}
+
+func TestWithCassetteKey(t *testing.T) {
+	// The prompts in a request's messages are chosen randomly, so only the task, answers and variant are in the key.
+	request := CompletionRequest{
+		Task:       DescriptionTask,
+		Messages:   []CompletionMessage{{Role: UserRole, Content: "a randomly chosen prompt"}},
+		Attributes: []string{"red", "velvet"},
+		Variant:    2,
+	}
+	key, _ := withCassetteKey(context.Background(), request).Value(cassetteKeyContextKey{}).(cassetteKey)
+	want := cassetteKey{Task: "description", Answers: []string{"red", "velvet"}, Variant: 2}
+	if !key.equal(want) {
+		t.Errorf("withCassetteKey() stored %+v, want %+v", key, want)
+	}
+}
+
+func TestCassetteReplay(t *testing.T) {
+	key := cassetteKey{Task: "description", Answers: []string{"red"}}
+	entries := []cassetteEntry{
+		{Key: key, StatusCode: http.StatusOK, ContentType: "application/json", Body: "first"},
+		{Key: cassetteKey{Task: "reply", Answers: []string{"red"}}, StatusCode: http.StatusOK, Body: "reply"},
+		{Key: key, StatusCode: http.StatusOK, ContentType: "application/json", Body: "second"},
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cassette, err := NewReplayingCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay := func(request CompletionRequest) (int, string) {
+		ctx := withCassetteKey(context.Background(), request)
+		httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost/v1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := cassette.RoundTrip(httpRequest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		return response.StatusCode, string(body)
+	}
+
+	// Responses recorded for the same key are replayed in order, and the last one is repeated once they run out.
+	description := CompletionRequest{Task: DescriptionTask, Attributes: []string{"red"}}
+	for _, want := range []string{"first", "second", "second"} {
+		if statusCode, body := replay(description); statusCode != http.StatusOK || body != want {
+			t.Errorf("RoundTrip() = %d %q, want %d %q", statusCode, body, http.StatusOK, want)
+		}
+	}
+	if statusCode, _ := replay(CompletionRequest{Task: DescriptionTask, Attributes: []string{"blue"}}); statusCode !=
+		http.StatusNotFound {
+
+		t.Errorf("RoundTrip() for an unrecorded request = %d, want %d", statusCode, http.StatusNotFound)
+	}
+}
+
+// cassetteBackend is a DescriptionBackend that replays its completions from a cassette, as a backend that makes HTTP
+// requests would.
+type cassetteBackend struct {
+	cassette *Cassette
+}
+
+// Name implements DescriptionBackend.
+func (b *cassetteBackend) Name() string {
+	return "cassette"
+}
+
+// Local implements DescriptionBackend.
+func (b *cassetteBackend) Local() bool {
+	return true
+}
+
+// Complete implements DescriptionBackend by returning the body of the response recorded for the request.
+func (b *cassetteBackend) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	httpRequest, err := http.NewRequestWithContext(withCassetteKey(ctx, request), http.MethodPost,
+		"http://localhost/v1", nil)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+	response, err := b.cassette.RoundTrip(httpRequest)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return CompletionResponse{}, newGenerationError(ErrUnavailable, errors.New(response.Status))
+	}
+	body, err := io.ReadAll(response.Body)
+	return CompletionResponse{Content: string(body)}, err
+}
+
+func TestCassetteReplayPrompts(t *testing.T) {
+	// Each set of answers gets its own recorded prompt.
+	entries := []cassetteEntry{
+		{Key: cassetteKey{Task: "prompt", Answers: []string{"red"}}, StatusCode: http.StatusOK, Body: "Why red?"},
+		{Key: cassetteKey{Task: "prompt", Answers: []string{"blue"}}, StatusCode: http.StatusOK, Body: "Why blue?"},
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cassette, err := NewReplayingCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packs := []messages.Pack{{Name: "prompts", Prompts: []messages.Prompt{{Text: "A color?"}}}}
+	messageProvider, err := messages.NewMessageProvider(messages.CosmicHorrorPersona, messages.DefaultLanguage, packs,
+		1, rand.New(rand.NewPCG(1, 0)), "", 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generator := NewCreatureGeneratorWithBackends(messageProvider, []DescriptionBackend{&cassetteBackend{cassette}},
+		nil, nil, config.Config{})
+
+	for _, answer := range []string{"red", "blue", "red"} {
+		offerings := []Offering{{Prompt: "A color?", Response: answer}}
+		prompt, err := generator.GeneratePrompt(context.Background(), offerings)
+		if want := "Why " + answer + "?"; err != nil || prompt != want {
+			t.Errorf("GeneratePrompt() for %q = %q, %v, want %q", answer, prompt, err, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package gen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/log"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/messages"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxReplyLength is the maximum number of characters in one of the creature's replies.
+const maxReplyLength = 600
+
+// ConversationTurn is a single exchange between the player and the summoned creature.
+type ConversationTurn struct {
+	PlayerMessage string
+	CreatureReply string
+}
+
+// GenerateReply generates the creature's reply to the last of the given conversation turns, in character, using the
+// creature and the offerings it was summoned with as context. The last turn's CreatureReply is ignored. It makes a
+// single attempt with the configured backend, since the player is waiting on it, and falls back to an offline reply if
+// that fails.
+func (g *CreatureGenerator) GenerateReply(ctx context.Context, creature Creature, offerings []Offering,
+	turns []ConversationTurn) (string, error) {
+
+	if len(turns) == 0 {
+		return "", newGenerationError(ErrInvalidRequest, errors.New("there is nothing to reply to"))
+	}
+
+	creatureJson, err := json.Marshal(creature)
+	if err != nil {
+		return "", newGenerationError(ErrInvalidRequest, err)
+	}
+
+	var attributes []string
+	for _, offering := range offerings {
+		attributes = append(attributes, offering.Response)
+	}
+
+	request := CompletionRequest{
+		Task: ReplyTask,
+		Messages: []CompletionMessage{
+			{
+				Role: SystemRole,
+				Content: g.withLanguageInstruction(g.messageProvider.GetMessage(messages.CreatureConversationPrompt) +
+					string(creatureJson) + "\n\n" +
+					g.messageProvider.GetMessage(messages.CreatureConversationOfferingsPrompt) +
+					formatOfferings(offerings)),
+			},
+		},
+		Language: g.messageProvider.Language(),
+		Sampling: g.sampling,
+	}
+	for i, turn := range turns {
+		request.Messages = append(request.Messages, CompletionMessage{
+			Role:    UserRole,
+			Content: sanitizeOffering(turn.PlayerMessage),
+		})
+		attributes = append(attributes, turn.PlayerMessage)
+		if i < len(turns)-1 {
+			request.Messages = append(request.Messages, CompletionMessage{
+				Role:    AssistantRole,
+				Content: turn.CreatureReply,
+			})
+		}
+	}
+	request.Attributes = attributes
+
+	backend := g.activeBackend()
+	reply, err := g.generateReplyWithBackend(ctx, backend, request)
+	if err == nil {
+		g.health.recordSuccess(backend)
+		return reply, nil
+	}
+	if errors.Is(err, ErrCanceled) {
+		return "", err
+	}
+	g.health.recordFailure(backend, err)
+	if _, isOffline := backend.(*OfflineBackend); isOffline {
+		return "", err
+	}
+
+	// Fall back to an offline reply, so the creature always answers. The original context may already be done, so it
+	// isn't used here.
+	reply, offlineErr := g.generateReplyWithBackend(context.Background(), g.offlineBackend, request)
+	if offlineErr != nil {
+		return "", errors.Join(err, offlineErr)
+	}
+	return reply, nil
+}
+
+// generateReplyWithBackend generates the creature's reply using the given backend. The returned error wraps one of the
+// causes defined in errors.go.
+func (g *CreatureGenerator) generateReplyWithBackend(ctx context.Context, backend DescriptionBackend,
+	request CompletionRequest) (string, error) {
+
+	response, err := backend.Complete(ctx, request)
+	if err == nil {
+		g.recordUsage(backend, request.Task, response)
+		response.Content, err = cleanReply(response.Content)
+	}
+	_, isOffline := backend.(*OfflineBackend)
+	if err == nil && !isOffline && g.moderate(ctx, response.Content).Flagged {
+		err = newGenerationError(ErrModerated, errors.New("the reply was flagged"))
+	}
+	if err != nil {
+		err = classifyError(err)
+		log.Logger.Print(fmt.Sprintf("func=\"gen.CreatureGenerator.generateReplyWithBackend\", msg=\"Backend "+
+			"failed.\", backend=\"%s\", error=\"%v\"", backend.Name(), err))
+		return "", err
+	}
+
+	log.Logger.Print(fmt.Sprintf("func=\"gen.CreatureGenerator.generateReplyWithBackend\", msg=\"Reply generated.\", "+
+		"backend=\"%s\", model=\"%s\"", backend.Name(), response.Model))
+	return response.Content, nil
+}
+
+// cleanReply trims the given generated reply and checks that it's usable.
+func cleanReply(reply string) (string, error) {
+	reply = strings.TrimSpace(reply)
+	switch {
+	case len(reply) == 0:
+		return "", newGenerationError(ErrEmptyResponse, errors.New("the reply is empty"))
+	case utf8.RuneCountInString(reply) > maxReplyLength:
+		return "", newGenerationError(ErrInvalidResponse, errors.New("the reply is too long"))
+	}
+	return reply, nil
+}
@@ -0,0 +1,274 @@
+package gen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Creature is a creature summoned by the player.
+type Creature struct {
+	Appearance     string   `json:"appearance"`
+	Name           string   `json:"name"`
+	Epithet        string   `json:"epithet"`
+	Size           string   `json:"size"`
+	Habitat        string   `json:"habitat"`
+	Abilities      []string `json:"abilities"`
+	Temperament    string   `json:"temperament"`
+	DangerRating   int      `json:"danger_rating"`
+	FateOfSummoner string   `json:"fate_of_summoner"`
+
+	// Attributions link phrases of the description to the ritual answers that inspired them. They're optional, and
+	// ones whose phrase can't be found in the description are ignored.
+	Attributions []Attribution `json:"attributions,omitempty"`
+
+	// Language is the language the creature is described in, as a language tag such as "es". It chooses the language
+	// of the narration that Render weaves the fields into. It isn't part of the creature's JSON.
+	Language string `json:"-"`
+}
+
+// Attribution links a phrase of a creature's description to the ritual answer that inspired it.
+type Attribution struct {
+	Phrase string `json:"phrase"`
+
+	// Answer is the number of the answer that inspired the phrase, starting from 1.
+	Answer int `json:"answer"`
+}
+
+// AttributionSpan is the position of an attributed phrase within the text returned by Creature.Render.
+type AttributionSpan struct {
+	// Start and End are the offsets of the phrase, in characters rather than bytes.
+	Start int
+	End   int
+
+	// Answer is the number of the answer that inspired the phrase, starting from 1.
+	Answer int
+}
+
+// minDangerRating and maxDangerRating are the bounds of a creature's danger rating.
+const (
+	minDangerRating = 1
+	maxDangerRating = 10
+)
+
+// creatureSentences are the sentences that Render weaves a creature's fields into, in a particular language. Each one
+// is a format string for fmt.Sprintf.
+type creatureSentences struct {
+	nameAndEpithet          string
+	name                    string
+	sizeAndHabitat          string
+	size                    string
+	habitat                 string
+	temperamentAndAbilities string
+	temperament             string
+	abilities               string
+
+	// danger describes a creature's danger rating, from least to most dangerous. Each sentence covers two points of
+	// the rating.
+	danger []string
+
+	// and is the word used to join the last item of a list, and serialComma is whether a comma comes before it when
+	// there are more than two items.
+	and         string
+	serialComma bool
+}
+
+// englishCreatureSentences are the creatureSentences for English, which are also used for languages that don't have
+// their own.
+var englishCreatureSentences = creatureSentences{
+	nameAndEpithet:          "You know it at once as %s, %s.",
+	name:                    "You know it at once as %s.",
+	sizeAndHabitat:          "It is %s, and it hails from %s.",
+	size:                    "It is %s.",
+	habitat:                 "It hails from %s.",
+	temperamentAndAbilities: "By nature it is %s, and it can %s.",
+	temperament:             "By nature it is %s.",
+	abilities:               "It can %s.",
+	danger: []string{
+		"It seems almost harmless, which is exactly what worries you.",
+		"It is dangerous, though perhaps not to you. Not yet.",
+		"Everything about it is a warning.",
+		"Its mere presence makes the walls sweat with fear.",
+		"It is a calamity given flesh, and the world will not survive its hunger.",
+	},
+	and:         "and",
+	serialComma: true,
+}
+
+// creatureSentencesByLanguage contains the creatureSentences for each supported language, keyed by language tag.
+var creatureSentencesByLanguage = map[string]creatureSentences{
+	"en": englishCreatureSentences,
+	"es": {
+		nameAndEpithet:          "La reconoces al instante como %s, %s.",
+		name:                    "La reconoces al instante como %s.",
+		sizeAndHabitat:          "Es %s, y procede de %s.",
+		size:                    "Es %s.",
+		habitat:                 "Procede de %s.",
+		temperamentAndAbilities: "Por naturaleza es %s, y puede %s.",
+		temperament:             "Por naturaleza es %s.",
+		abilities:               "Puede %s.",
+		danger: []string{
+			"Parece casi inofensiva, y eso es justo lo que te inquieta.",
+			"Es peligrosa, aunque quizá no para ti. Todavía no.",
+			"Todo en ella es una advertencia.",
+			"Su mera presencia hace que las paredes suden de miedo.",
+			"Es una calamidad hecha carne, y el mundo no sobrevivirá a su hambre.",
+		},
+		and:         "y",
+		serialComma: false,
+	},
+}
+
+// Render returns the creature's description as a single paragraph of narration. Fields that are empty are left out,
+// so a partially generated creature can be rendered as well.
+func (c Creature) Render() string {
+	narration, ok := creatureSentencesByLanguage[c.Language]
+	if !ok {
+		narration = englishCreatureSentences
+	}
+
+	var sentences []string
+	addSentence := func(format string, args ...any) {
+		sentences = append(sentences, fmt.Sprintf(format, args...))
+	}
+
+	if len(c.Appearance) > 0 {
+		addSentence("%s", asSentences(c.Appearance))
+	}
+
+	switch {
+	case len(c.Name) > 0 && len(c.Epithet) > 0:
+		addSentence(narration.nameAndEpithet, c.Name, c.Epithet)
+	case len(c.Name) > 0:
+		addSentence(narration.name, c.Name)
+	}
+
+	switch {
+	case len(c.Size) > 0 && len(c.Habitat) > 0:
+		addSentence(narration.sizeAndHabitat, c.Size, c.Habitat)
+	case len(c.Size) > 0:
+		addSentence(narration.size, c.Size)
+	case len(c.Habitat) > 0:
+		addSentence(narration.habitat, c.Habitat)
+	}
+
+	switch {
+	case len(c.Temperament) > 0 && len(c.Abilities) > 0:
+		addSentence(narration.temperamentAndAbilities, c.Temperament, narration.joinList(c.Abilities))
+	case len(c.Temperament) > 0:
+		addSentence(narration.temperament, c.Temperament)
+	case len(c.Abilities) > 0:
+		addSentence(narration.abilities, narration.joinList(c.Abilities))
+	}
+
+	if c.DangerRating >= minDangerRating && c.DangerRating <= maxDangerRating {
+		addSentence("%s", narration.danger[(c.DangerRating-1)/2])
+	}
+
+	if len(c.FateOfSummoner) > 0 {
+		addSentence("%s", asSentences(c.FateOfSummoner))
+	}
+
+	return strings.Join(sentences, " ")
+}
+
+// AttributionSpans returns the positions of the creature's attributed phrases within the text returned by Render,
+// ordered by position. Phrases are matched as whole words, ignoring case. Attributions whose phrase can't be found, or
+// that overlap an earlier one, are left out.
+func (c Creature) AttributionSpans() []AttributionSpan {
+	text := c.Render()
+
+	// Case is only ignored if lowercasing leaves the length of the text unchanged, so offsets still line up.
+	searchText := strings.ToLower(text)
+	ignoreCase := len(searchText) == len(text)
+	if !ignoreCase {
+		searchText = text
+	}
+
+	var spans []AttributionSpan
+	for _, attribution := range c.Attributions {
+		phrase := strings.TrimSpace(attribution.Phrase)
+		if len(phrase) == 0 || attribution.Answer < 1 {
+			continue
+		}
+		if ignoreCase {
+			phrase = strings.ToLower(phrase)
+		}
+
+		start := findWholeWords(searchText, phrase)
+		if start == -1 {
+			continue
+		}
+		span := AttributionSpan{
+			Start:  utf8.RuneCountInString(text[:start]),
+			End:    utf8.RuneCountInString(text[:start+len(phrase)]),
+			Answer: attribution.Answer,
+		}
+		overlaps := slices.ContainsFunc(spans, func(other AttributionSpan) bool {
+			return span.Start < other.End && other.Start < span.End
+		})
+		if !overlaps {
+			spans = append(spans, span)
+		}
+	}
+
+	slices.SortFunc(spans, func(a, b AttributionSpan) int { return a.Start - b.Start })
+	return spans
+}
+
+// findWholeWords returns the byte offset of the first occurrence of the given phrase in the given text that isn't
+// part of a longer word, or -1 if there is none.
+func findWholeWords(text, phrase string) int {
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }
+	for offset := 0; offset < len(text); {
+		index := strings.Index(text[offset:], phrase)
+		if index == -1 {
+			return -1
+		}
+		start := offset + index
+		end := start + len(phrase)
+
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (start == 0 || !isWordRune(before)) && (end == len(text) || !isWordRune(after)) {
+			return start
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		offset = start + size
+	}
+	return -1
+}
+
+// asSentences returns the given text with surrounding whitespace removed, ending in punctuation.
+func asSentences(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) == 0 || strings.ContainsAny(text[len(text)-1:], ".!?\"'") {
+		return text
+	}
+	return text + "."
+}
+
+// joinList joins the given items into an English list, such as "a, b, and c".
+func joinList(items []string) string {
+	return englishCreatureSentences.joinList(items)
+}
+
+// joinList joins the given items into a list in the sentences' language, such as "a, b, and c".
+func (s creatureSentences) joinList(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	case 2:
+		return items[0] + " " + s.and + " " + items[1]
+	}
+
+	separator := " "
+	if s.serialComma {
+		separator = ", "
+	}
+	return strings.Join(items[:len(items)-1], ", ") + separator + s.and + " " + items[len(items)-1]
+}
@@ -2,49 +2,506 @@ package gen
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/config"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/log"
 	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/messages"
-	"github.com/sashabaranov/go-openai"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/usage"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // CreatureGenerator generates creature descriptions and images.
 type CreatureGenerator struct {
 	messageProvider *messages.MessageProvider
-	openAiClient    *openai.Client
+	backends        []DescriptionBackend
+	offlineBackend  DescriptionBackend
+	health          *backendHealth
+	summoningWindow time.Duration
+	sampling        config.Sampling
+	localOnly       atomic.Bool
+	moderator       Moderator
+	localModerator  Moderator
+	usageTracker    *usage.Tracker
+	overBudget      atomic.Bool
 }
 
-// NewCreatureGenerator creates a new CreatureGenerator with the given OpenAI API key.
-func NewCreatureGenerator(messageProvider *messages.MessageProvider, apiKey string) *CreatureGenerator {
-	return &CreatureGenerator{
+// NewCreatureGenerator creates a new CreatureGenerator that uses the backend and fallbacks described by the given
+// configuration. In privacy mode, backends that aren't local are left out. If every backend is local, the blocklist
+// moderator is used instead of the OpenAI moderator.
+func NewCreatureGenerator(messageProvider *messages.MessageProvider,
+	gameConfig config.Config) (*CreatureGenerator, error) {
+
+	var backends []DescriptionBackend
+	for _, backendConfig := range append([]config.Backend{gameConfig.Backend}, gameConfig.Fallbacks...) {
+		backend, err := NewBackend(backendConfig)
+		if err != nil {
+			return nil, err
+		}
+		if gameConfig.PrivacyMode && !backend.Local() {
+			log.Logger.Print(fmt.Sprintf("func=\"gen.NewCreatureGenerator\", msg=\"Privacy mode is on, so the "+
+				"backend is left out.\", backend=\"%s\"", backend.Name()))
+			continue
+		}
+		backends = append(backends, backend)
+	}
+
+	// A local backend's text shouldn't be sent over the network to be moderated either.
+	moderationConfig := gameConfig.Moderation
+	allLocal := !slices.ContainsFunc(backends, func(backend DescriptionBackend) bool { return !backend.Local() })
+	if allLocal && moderationConfig.Type == config.OpenAiModerator {
+		moderationConfig.Type = config.BlocklistModerator
+	}
+	moderator, err := NewModerator(moderationConfig, gameConfig.Backend, messageProvider.Language())
+	if err != nil {
+		return nil, err
+	}
+	usageTracker, err := usage.NewTracker(gameConfig.Usage)
+	if err != nil {
+		return nil, err
+	}
+	return NewCreatureGeneratorWithBackends(messageProvider, backends, moderator, usageTracker, gameConfig), nil
+}
+
+// NewCreatureGeneratorWithBackends creates a new CreatureGenerator that uses the given backends, moderator and usage
+// tracker, ignoring the ones described by the given configuration. The backends are tried in order, and if they all
+// fail, the offline backend is used instead. If the moderator is nil, generated text isn't moderated, and if the usage
+// tracker is nil, usage isn't tracked.
+func NewCreatureGeneratorWithBackends(messageProvider *messages.MessageProvider, backends []DescriptionBackend,
+	moderator Moderator, usageTracker *usage.Tracker, gameConfig config.Config) *CreatureGenerator {
+
+	generator := &CreatureGenerator{
 		messageProvider: messageProvider,
-		openAiClient:    openai.NewClient(apiKey),
+		backends:        backends,
+		offlineBackend:  NewOfflineBackend(),
+		health:          newBackendHealth(gameConfig.Health),
+		summoningWindow: time.Duration(gameConfig.Summoning.MaxDuration),
+		sampling:        gameConfig.Sampling,
+		moderator:       moderator,
+		localModerator: NewBlocklistModerator(gameConfig.Moderation.FamilyFriendly,
+			gameConfig.Moderation.BlockedWords, messageProvider.Language()),
+		usageTracker: usageTracker,
+	}
+	generator.localOnly.Store(gameConfig.PrivacyMode)
+	generator.overBudget.Store(usageTracker != nil && usageTracker.OverBudget())
+	return generator
+}
+
+// SetLocalOnly sets whether the generator is restricted to local backends. While it is, only the configured backends
+// that are local are used, and the offline backend is used otherwise, so nothing leaves the machine.
+func (g *CreatureGenerator) SetLocalOnly(localOnly bool) {
+	g.localOnly.Store(localOnly)
+}
+
+// IsLocal returns whether everything the generator does stays on this machine. Backends that have recently failed are
+// taken into account, since they may be tried again later.
+func (g *CreatureGenerator) IsLocal() bool {
+	return !slices.ContainsFunc(g.availableBackends(), func(backend DescriptionBackend) bool {
+		return !backend.Local()
+	})
+}
+
+// availableBackends returns the backends that may be used for generation, in order, taking into account whether the
+// generator is restricted to local backends, and whether the usage budget has been exceeded.
+func (g *CreatureGenerator) availableBackends() []DescriptionBackend {
+	if !g.localOnly.Load() && !g.overBudget.Load() {
+		return g.backends
+	}
+
+	var backends []DescriptionBackend
+	for _, backend := range g.backends {
+		if backend.Local() {
+			backends = append(backends, backend)
+		}
 	}
+	return backends
 }
 
-// GenerateDescription generates a description of the creature being summoned, based on the given attributes.
-func (g *CreatureGenerator) GenerateDescription(ctx context.Context, creatureAttributes []string) string {
-	var creatureAttributesList string
-	for i, creatureAttribute := range creatureAttributes {
-		creatureAttributesList += strings.ReplaceAll(creatureAttribute, ",", " ")
-		if i < len(creatureAttributes)-1 {
-			creatureAttributesList += ", "
+// healthyBackends returns the available backends, in order, leaving out those that have recently failed.
+func (g *CreatureGenerator) healthyBackends() []DescriptionBackend {
+	var backends []DescriptionBackend
+	for _, backend := range g.availableBackends() {
+		if g.health.isHealthy(backend) {
+			backends = append(backends, backend)
+		} else {
+			log.Logger.Print(fmt.Sprintf("func=\"gen.CreatureGenerator.healthyBackends\", msg=\"Backend failed "+
+				"recently, so it's skipped.\", backend=\"%s\"", backend.Name()))
 		}
 	}
+	return backends
+}
+
+// activeBackend returns the backend to use for a single generation: the first healthy backend, or the offline backend
+// if there isn't one.
+func (g *CreatureGenerator) activeBackend() DescriptionBackend {
+	if backends := g.healthyBackends(); len(backends) > 0 {
+		return backends[0]
+	}
+	return g.offlineBackend
+}
 
-	request := openai.ChatCompletionRequest{
-		Model: openai.GPT3Dot5Turbo,
-		Messages: []openai.ChatCompletionMessage{
+// recordUsage records the usage of the given response from the given backend for the given task. Local backends are
+// free, so their usage isn't recorded. Once the usage budget has been exceeded, the offline backend is used instead of
+// the configured backend.
+func (g *CreatureGenerator) recordUsage(backend DescriptionBackend, task Task, response CompletionResponse) {
+	if g.usageTracker == nil || backend.Local() {
+		return
+	}
+
+	g.usageTracker.Record(task.String(), response.Model, response.Usage)
+	if g.usageTracker.OverBudget() && !g.overBudget.Swap(true) {
+		log.Logger.Print(fmt.Sprintf("func=\"gen.CreatureGenerator.recordUsage\", msg=\"The usage budget has been "+
+			"exceeded, so the offline backend is used from now on.\", backend=\"%s\"", backend.Name()))
+	}
+}
+
+// activeModerator returns the moderator to use, taking into account whether the generator is restricted to local
+// backends, or nil if there's no moderator.
+func (g *CreatureGenerator) activeModerator() Moderator {
+	if g.moderator != nil && g.localOnly.Load() && !g.moderator.Local() {
+		return g.localModerator
+	}
+	return g.moderator
+}
+
+// moderate checks the given text with the active moderator. If the moderator fails, the local moderator is used
+// instead. If there's no moderator, the text is never flagged.
+func (g *CreatureGenerator) moderate(ctx context.Context, text string) ModerationResult {
+	moderator := g.activeModerator()
+	if moderator == nil {
+		return ModerationResult{}
+	}
+
+	result, err := moderator.Moderate(ctx, text)
+	if err != nil {
+		log.Logger.Print(fmt.Sprintf("func=\"gen.CreatureGenerator.moderate\", msg=\"Moderator failed, so the "+
+			"local moderator is used instead.\", moderator=\"%s\", error=\"%v\"", moderator.Name(), err))
+		result, _ = g.localModerator.Moderate(ctx, text)
+	}
+	if result.Flagged && len(result.Categories) == 0 {
+		result.Categories = []string{"inappropriate"}
+	}
+	if result.Flagged {
+		log.Logger.Print(fmt.Sprintf("func=\"gen.CreatureGenerator.moderate\", msg=\"Text was flagged.\", "+
+			"moderator=\"%s\", categories=\"%s\"", moderator.Name(), strings.Join(result.Categories, ", ")))
+	}
+	return result
+}
+
+// moderateUpdates wraps the given onUpdate function so the player never sees text that would be flagged. If the
+// moderator is local, each update is checked as it arrives, and updates stop once one is flagged. Otherwise, updates
+// are withheld entirely, since checking each one remotely would be too slow.
+func (g *CreatureGenerator) moderateUpdates(ctx context.Context, onUpdate func(text string)) func(text string) {
+	moderator := g.activeModerator()
+	if moderator == nil || onUpdate == nil {
+		return onUpdate
+	}
+	if !moderator.Local() {
+		return nil
+	}
+
+	flagged := false
+	return func(text string) {
+		if !flagged {
+			flagged = g.moderate(ctx, text).Flagged
+		}
+		if !flagged {
+			onUpdate(text)
+		}
+	}
+}
+
+// withLanguageInstruction returns the given instructions for the language model, followed by an instruction to write
+// in the player's language, if it isn't the default language.
+func (g *CreatureGenerator) withLanguageInstruction(instructions string) string {
+	if languageInstruction := g.messageProvider.LanguageInstruction(); len(languageInstruction) > 0 {
+		return instructions + "\n\n" + languageInstruction
+	}
+	return instructions
+}
+
+// GenerateCreature generates the creature being summoned, based on the player's responses to the given offerings. The
+// categories of the offerings tell the generator what kind of attribute each response describes. If onUpdate is not
+// nil and the backend supports streaming, onUpdate is called with the creature's description as generated so far each
+// time more of it arrives. The text passed to onUpdate is never empty, but it may be replaced entirely if the backend
+// fails partway through.
+func (g *CreatureGenerator) GenerateCreature(ctx context.Context, offerings []Offering,
+	onUpdate func(text string)) (Creature, error) {
+
+	return g.generateCreature(ctx, offerings, 0, onUpdate)
+}
+
+// generateCreature generates the given variant of the creature being summoned, as described by GenerateCreature.
+func (g *CreatureGenerator) generateCreature(ctx context.Context, offerings []Offering, variant int,
+	onUpdate func(text string)) (Creature, error) {
+
+	creatureAttributes := make([]string, len(offerings))
+	categories := make([]messages.Category, len(offerings))
+	for i, offering := range offerings {
+		creatureAttributes[i] = offering.Response
+		categories[i] = offering.Category
+	}
+
+	request := CompletionRequest{
+		Task: DescriptionTask,
+		Messages: []CompletionMessage{
+			{
+				Role:    SystemRole,
+				Content: g.withLanguageInstruction(g.messageProvider.GetMessage(messages.CreatureDescriptionPrompt)),
+			},
 			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: g.messageProvider.GetMessage(messages.CreatureDescriptionPrompt) + creatureAttributesList,
+				Role: UserRole,
+				Content: g.messageProvider.GetMessage(messages.CreatureAttributesPrompt) +
+					formatResponses(offerings),
 			},
 		},
+		Attributes: creatureAttributes,
+		Categories: categories,
+		Language:   g.messageProvider.Language(),
+		Variant:    variant,
+		JsonOutput: true,
+		Sampling:   g.sampling,
+	}
+
+	start := time.Now()
+	backends := g.healthyBackends()
+	var errs []error
+	for i, backend := range backends {
+		// Every backend but the last gets an equal share of what's left of the summoning window to begin responding,
+		// so that the next backend still has time if it doesn't. Once the window has run out, those backends are
+		// skipped, since a timeout of zero would mean waiting on them for as long as they take.
+		var responseTimeout time.Duration
+		if i < len(backends)-1 && g.summoningWindow > 0 {
+			responseTimeout = (g.summoningWindow - time.Since(start)) / time.Duration(len(backends)-i)
+			if responseTimeout <= 0 {
+				log.Logger.Print(fmt.Sprintf("func=\"gen.CreatureGenerator.generateCreature\", msg=\"The summoning "+
+					"window has run out, so the backend is skipped.\", backend=\"%s\"", backend.Name()))
+				continue
+			}
+		}
+
+		creature, err := g.generateCreatureWithTimeout(ctx, backend, request, g.moderateUpdates(ctx, onUpdate),
+			responseTimeout)
+		if err == nil {
+			g.health.recordSuccess(backend)
+			return creature, nil
+		}
+		if errors.Is(err, ErrModerated) {
+			// A creature that was flagged by moderation isn't replaced, since the game shows a refusal instead.
+			return creature, err
+		}
+
+		// The caller may cancel the context with ErrTimeout as the cause, to stop waiting on a backend that's taking
+		// too long. That's treated as a timeout rather than the player giving up.
+		if errors.Is(err, ErrCanceled) && errors.Is(context.Cause(ctx), ErrTimeout) {
+			err = newGenerationError(ErrTimeout, err)
+		} else if errors.Is(err, ErrCanceled) && ctx.Err() != nil {
+			return Creature{}, err
+		}
+		g.health.recordFailure(backend, err)
+		errs = append(errs, err)
+
+		if _, isOffline := backend.(*OfflineBackend); isOffline {
+			return Creature{}, errors.Join(errs...)
+		}
+		if ctx.Err() != nil {
+			// The summoning window is over, so there's no time left for the other backends.
+			break
+		}
+	}
+
+	// Fall back to generating the creature offline, so the summoning still has a payoff. The original context may
+	// already be done, so it isn't used here.
+	creature, offlineErr := g.generateCreatureWithBackend(context.Background(), g.offlineBackend, request, nil)
+	if offlineErr != nil {
+		return Creature{}, errors.Join(append(errs, offlineErr)...)
+	}
+	return creature, nil
+}
+
+// GenerateCandidates generates the given number of candidate creatures concurrently, based on the given offerings, so
+// the player can choose between them. Each candidate is generated like GenerateCreature, but without streaming.
+// Candidates that fail, or that share a name with an earlier candidate, are left out. An error is only returned if
+// every candidate fails.
+func (g *CreatureGenerator) GenerateCandidates(ctx context.Context, offerings []Offering,
+	count int) ([]Creature, error) {
+
+	creatures := make([]Creature, count)
+	errs := make([]error, count)
+	var waitGroup sync.WaitGroup
+	for i := range count {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			creatures[i], errs[i] = g.generateCreature(ctx, offerings, i, nil)
+		}()
+	}
+	waitGroup.Wait()
+
+	var candidates []Creature
+	for i, creature := range creatures {
+		isDuplicate := slices.ContainsFunc(candidates, func(candidate Creature) bool {
+			return candidate.Name == creature.Name
+		})
+		if errs[i] == nil && !isDuplicate {
+			candidates = append(candidates, creature)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return candidates, nil
+}
+
+// generateCreatureWithTimeout generates a creature using the given backend, like generateCreatureWithBackend. If
+// responseTimeout isn't zero and the backend hasn't begun responding within it, generation is canceled with ErrTimeout.
+// If onUpdate is nil, the backend hasn't begun responding until it has finished.
+func (g *CreatureGenerator) generateCreatureWithTimeout(ctx context.Context, backend DescriptionBackend,
+	request CompletionRequest, onUpdate func(text string), responseTimeout time.Duration) (Creature, error) {
+
+	if responseTimeout <= 0 {
+		return g.generateCreatureWithBackend(ctx, backend, request, onUpdate)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	responseTimer := time.AfterFunc(responseTimeout, func() { cancel(ErrTimeout) })
+	defer responseTimer.Stop()
+
+	if onUpdate != nil {
+		update := onUpdate
+		onUpdate = func(text string) {
+			responseTimer.Stop()
+			update(text)
+		}
+	}
+
+	creature, err := g.generateCreatureWithBackend(ctx, backend, request, onUpdate)
+	if errors.Is(err, ErrCanceled) && errors.Is(context.Cause(ctx), ErrTimeout) {
+		err = newGenerationError(ErrTimeout, err)
 	}
+	return creature, err
+}
+
+// maxCorrections is the maximum number of times a backend is asked to correct a creature that failed validation.
+const maxCorrections = 2
+
+// minCorrectionTime is the minimum time that must remain before the context's deadline for a correction to be asked
+// for.
+const minCorrectionTime = 5 * time.Second
+
+// generateCreatureWithBackend generates a creature using the given backend. If the creature doesn't follow the
+// instructions or is flagged by moderation, the backend is asked to correct it while time remains. The returned error
+// wraps one of the causes defined in errors.go.
+func (g *CreatureGenerator) generateCreatureWithBackend(ctx context.Context, backend DescriptionBackend,
+	request CompletionRequest, onUpdate func(text string)) (Creature, error) {
+
+	var previousCreature Creature
+	var previousFlagged bool
+	for corrections := 0; ; corrections++ {
+		creature, response, err := g.attemptCreature(ctx, backend, request, onUpdate)
+		if err != nil && corrections > 0 && !previousFlagged {
+			// The creature that needed correcting is better than nothing.
+			return normalizeCreature(previousCreature), nil
+		}
+		if err != nil && corrections > 0 {
+			return Creature{}, newGenerationError(ErrModerated, err)
+		}
+		if err != nil {
+			return Creature{}, err
+		}
+
+		problems := validateCreature(creature)
+		var moderation ModerationResult
+		if _, isOffline := backend.(*OfflineBackend); !isOffline {
+			moderation = g.moderate(ctx, creature.Render())
+		}
+		if moderation.Flagged {
+			problems = append(problems, fmt.Sprintf("The creature must not include anything that could be "+
+				"considered %s. Rewrite it without that content.", strings.Join(moderation.Categories, " or ")))
+		}
+		if len(problems) == 0 {
+			return creature, nil
+		}
+		log.Logger.Print(fmt.Sprintf("func=\"gen.CreatureGenerator.generateCreatureWithBackend\", "+
+			"msg=\"Creature failed validation.\", backend=\"%s\", corrections=\"%d\", problems=\"%s\"",
+			backend.Name(), corrections, strings.Join(problems, " ")))
+
+		deadline, hasDeadline := ctx.Deadline()
+		if corrections == maxCorrections || (hasDeadline && time.Until(deadline) < minCorrectionTime) {
+			if moderation.Flagged {
+				return Creature{}, newGenerationError(ErrModerated, errors.New("no correction was allowed"))
+			}
+			return normalizeCreature(creature), nil
+		}
+
+		// Ask for a correction without streaming it, so the description that's already shown doesn't start over.
+		request.Messages = append(slices.Clip(request.Messages),
+			CompletionMessage{Role: AssistantRole, Content: response.Content},
+			CompletionMessage{
+				Role: UserRole,
+				Content: g.messageProvider.GetMessage(messages.CreatureCorrectionPrompt) + "- " +
+					strings.Join(problems, "\n- "),
+			},
+		)
+		onUpdate = nil
+		previousCreature = creature
+		previousFlagged = moderation.Flagged
+	}
+}
+
+// attemptCreature generates a creature using the given backend, retrying transient failures. The returned error wraps
+// one of the causes defined in errors.go.
+func (g *CreatureGenerator) attemptCreature(ctx context.Context, backend DescriptionBackend,
+	request CompletionRequest, onUpdate func(text string)) (Creature, CompletionResponse, error) {
+
+	var creature Creature
+	var response CompletionResponse
+	err := defaultRetryPolicy.do(ctx, backend.Name(), func(ctx context.Context) error {
+		var err error
+		if streamingBackend, ok := backend.(StreamingBackend); ok && onUpdate != nil {
+			response, err = streamingBackend.CompleteStream(ctx, request, func(text string) {
+				if partialCreature, err := parseCreature(text, true); err == nil {
+					partialCreature.Language = request.Language
+					if description := partialCreature.Render(); len(description) > 0 {
+						onUpdate(description)
+					}
+				}
+			})
+		} else {
+			response, err = backend.Complete(ctx, request)
+		}
+		if err != nil {
+			return err
+		}
+		g.recordUsage(backend, request.Task, response)
+
+		creature, err = parseCreature(response.Content, false)
+		if err != nil {
+			return newGenerationError(ErrInvalidResponse, err)
+		}
+		creature.Language = request.Language
 
-	response, err := g.openAiClient.CreateChatCompletion(ctx, request)
+		// Attributions to answers that weren't given are made up, so they're dropped.
+		creature.Attributions = slices.DeleteFunc(creature.Attributions, func(attribution Attribution) bool {
+			return attribution.Answer > len(request.Attributes)
+		})
+		return nil
+	})
 	if err != nil {
-		return ""
+		log.Logger.Print(fmt.Sprintf("func=\"gen.CreatureGenerator.attemptCreature\", "+
+			"msg=\"Backend failed.\", backend=\"%s\", error=\"%v\"", backend.Name(), err))
+		return Creature{}, CompletionResponse{}, err
 	}
 
-	return response.Choices[0].Message.Content
+	log.Logger.Print(fmt.Sprintf("func=\"gen.CreatureGenerator.attemptCreature\", "+
+		"msg=\"Creature generated.\", backend=\"%s\", model=\"%s\", temperature=\"%g\", topP=\"%g\", "+
+		"maxTokens=\"%d\", name=\"%s\", dangerRating=\"%d\"", backend.Name(), response.Model,
+		request.Sampling.Temperature, request.Sampling.TopP, request.Sampling.MaxTokens, creature.Name,
+		creature.DangerRating))
+	return creature, response, nil
 }
@@ -0,0 +1,234 @@
+package gen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxAbilities is the maximum number of abilities a creature may have.
+const maxAbilities = 3
+
+// defaultDangerRating is the danger rating given to a creature whose danger rating is missing.
+const defaultDangerRating = 5
+
+// parseCreature parses a Creature from the given model output, repairing it where possible. Code fences, surrounding
+// text and truncated JSON are tolerated, field names are matched loosely, and field values are coerced to the expected
+// types. If partial is true, the output is assumed to still be arriving, so missing fields are left empty instead of
+// causing an error.
+func parseCreature(output string, partial bool) (Creature, error) {
+	start := strings.Index(output, "{")
+	if start == -1 {
+		return Creature{}, errors.New("the output contains no JSON object")
+	}
+
+	fields, err := parseJsonObject(output[start:])
+	if err != nil {
+		return Creature{}, err
+	}
+
+	creature := Creature{
+		Appearance:     cleanSentences(coerceString(fields["appearance"])),
+		Name:           cleanPhrase(coerceString(fields["name"])),
+		Epithet:        cleanPhrase(coerceString(fields["epithet"])),
+		Size:           cleanPhrase(coerceString(fields["size"])),
+		Habitat:        cleanPhrase(coerceString(fields["habitat"])),
+		Abilities:      coerceStrings(fields["abilities"]),
+		Temperament:    cleanPhrase(coerceString(fields["temperament"])),
+		DangerRating:   coerceInt(fields["dangerrating"]),
+		FateOfSummoner: cleanSentences(coerceString(fields["fateofsummoner"])),
+		Attributions:   coerceAttributions(fields["attributions"]),
+	}
+	if partial {
+		return creature, nil
+	}
+
+	if len(creature.Appearance) == 0 || len(creature.FateOfSummoner) == 0 {
+		return Creature{}, errors.New("the creature is missing its appearance or the fate of its summoner")
+	}
+	if len(creature.Abilities) > maxAbilities {
+		creature.Abilities = creature.Abilities[:maxAbilities]
+	}
+	if creature.DangerRating == 0 {
+		creature.DangerRating = defaultDangerRating
+	}
+	creature.DangerRating = max(minDangerRating, min(maxDangerRating, creature.DangerRating))
+
+	return creature, nil
+}
+
+// parseJsonObject parses the JSON object at the start of the given text into a map keyed by normalized field names. If
+// the object is truncated, it's closed off at the last point where it's valid.
+func parseJsonObject(text string) (map[string]any, error) {
+	candidate := closeJson(text)
+	for {
+		var object map[string]any
+		if err := json.Unmarshal([]byte(candidate), &object); err == nil {
+			fields := make(map[string]any, len(object))
+			for key, value := range object {
+				fields[normalizeFieldName(key)] = value
+			}
+			return fields, nil
+		}
+
+		// Cut the text back to the previous comma and try again.
+		cut := strings.LastIndex(text, ",")
+		if cut == -1 {
+			return nil, fmt.Errorf("the output contains invalid JSON: %q", text)
+		}
+		text = text[:cut]
+		candidate = closeJson(text)
+	}
+}
+
+// closeJson returns the first JSON value in the given text, closing any strings, arrays and objects that were left open
+// if the text ends partway through the value.
+func closeJson(text string) string {
+	var closers []rune
+	inString := false
+	escaped := false
+
+	for i, r := range text {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && r == '\\':
+			escaped = true
+		case r == '"':
+			inString = !inString
+		case inString:
+		case r == '{':
+			closers = append(closers, '}')
+		case r == '[':
+			closers = append(closers, ']')
+		case r == '}' || r == ']':
+			if len(closers) > 0 {
+				closers = closers[:len(closers)-1]
+			}
+			if len(closers) == 0 {
+				return text[:i+1]
+			}
+		}
+	}
+
+	if escaped {
+		text = text[:len(text)-1]
+	}
+	if inString {
+		text += "\""
+	}
+	text = strings.TrimRightFunc(text, unicode.IsSpace)
+	text = strings.TrimRight(text, ",:")
+	for i := len(closers) - 1; i >= 0; i-- {
+		text += string(closers[i])
+	}
+	return text
+}
+
+// normalizeFieldName lowercases the given field name and removes anything other than letters, so that variations such
+// as "dangerRating" and "danger_rating" are treated the same.
+func normalizeFieldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if !unicode.IsLetter(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// coerceString converts the given JSON value to a string.
+func coerceString(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case []any:
+		return joinList(coerceStrings(value))
+	default:
+		return ""
+	}
+}
+
+// coerceStrings converts the given JSON value to a list of phrases.
+func coerceStrings(value any) []string {
+	var items []string
+	switch value := value.(type) {
+	case []any:
+		for _, item := range value {
+			items = append(items, coerceString(item))
+		}
+	case string:
+		items = strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == '\n' })
+	}
+
+	var phrases []string
+	for _, item := range items {
+		if phrase := cleanPhrase(item); len(phrase) > 0 {
+			phrases = append(phrases, phrase)
+		}
+	}
+	return phrases
+}
+
+// coerceInt converts the given JSON value to an integer. Strings such as "7/10" are converted using their leading
+// digits.
+func coerceInt(value any) int {
+	switch value := value.(type) {
+	case float64:
+		return int(value)
+	case string:
+		digits := strings.TrimSpace(value)
+		end := strings.IndexFunc(digits, func(r rune) bool { return !unicode.IsDigit(r) })
+		if end != -1 {
+			digits = digits[:end]
+		}
+		number, _ := strconv.Atoi(digits)
+		return number
+	default:
+		return 0
+	}
+}
+
+// coerceAttributions converts the given JSON value to a list of attributions. Items that aren't objects, or that are
+// missing their phrase or answer, are skipped.
+func coerceAttributions(value any) []Attribution {
+	items, _ := value.([]any)
+
+	var attributions []Attribution
+	for _, item := range items {
+		object, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		fields := make(map[string]any, len(object))
+		for key, value := range object {
+			fields[normalizeFieldName(key)] = value
+		}
+
+		attribution := Attribution{
+			Phrase: cleanPhrase(coerceString(fields["phrase"])),
+			Answer: coerceInt(fields["answer"]),
+		}
+		if len(attribution.Phrase) > 0 && attribution.Answer > 0 {
+			attributions = append(attributions, attribution)
+		}
+	}
+	return attributions
+}
+
+// cleanPhrase removes Markdown formatting, line breaks, surrounding quotes and trailing punctuation from a phrase that
+// is inserted into a sentence.
+func cleanPhrase(phrase string) string {
+	phrase = trimQuotes(flattenParagraphs(stripMarkdown(phrase)))
+	return strings.TrimRight(phrase, ".!;,")
+}
+
+// cleanSentences removes Markdown formatting and surrounding quotes from one or more complete sentences. Paragraph
+// breaks are kept, so they can be caught by validateCreature.
+func cleanSentences(sentences string) string {
+	return trimQuotes(stripMarkdown(sentences))
+}
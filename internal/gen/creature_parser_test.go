@@ -0,0 +1,133 @@
+package gen
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseCreature(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		partial bool
+		want    Creature
+		wantErr bool
+	}{
+		{
+			name: "plain JSON",
+			output: `{"appearance": "A moth of velvet.", "name": "Vel", "abilities": ["flight"], ` +
+				`"danger_rating": 4, "fate_of_summoner": "It eats you."}`,
+			want: Creature{Appearance: "A moth of velvet.", Name: "Vel", Abilities: []string{"flight"},
+				DangerRating: 4, FateOfSummoner: "It eats you."},
+		},
+		{
+			name: "code fence and surrounding text",
+			output: "Here is your creature:\n```json\n{\"appearance\": \"A moth.\", \"fate_of_summoner\": " +
+				"\"It eats you.\"}\n```\nEnjoy!",
+			want: Creature{Appearance: "A moth.", DangerRating: defaultDangerRating, FateOfSummoner: "It eats you."},
+		},
+		{
+			name:   "loosely matched field names",
+			output: `{"Appearance": "A moth.", "dangerRating": 2, "Fate Of Summoner": "It eats you."}`,
+			want:   Creature{Appearance: "A moth.", DangerRating: 2, FateOfSummoner: "It eats you."},
+		},
+		{
+			name: "coerced values",
+			output: `{"appearance": "A moth.", "danger_rating": "7", "abilities": "flight", ` +
+				`"fate_of_summoner": "Gone."}`,
+			want: Creature{Appearance: "A moth.", Abilities: []string{"flight"}, DangerRating: 7,
+				FateOfSummoner: "Gone."},
+		},
+		{
+			name: "Markdown formatting",
+			output: `{"appearance": "**A moth** of [velvet](https://example.com).", "name": "*Vel*.", ` +
+				`"fate_of_summoner": "> It eats you."}`,
+			want: Creature{Appearance: "A moth of velvet.", Name: "Vel", DangerRating: defaultDangerRating,
+				FateOfSummoner: "It eats you."},
+		},
+		{
+			name:   "danger rating out of range",
+			output: `{"appearance": "A moth.", "danger_rating": 42, "fate_of_summoner": "Gone."}`,
+			want:   Creature{Appearance: "A moth.", DangerRating: maxDangerRating, FateOfSummoner: "Gone."},
+		},
+		{
+			name: "too many abilities",
+			output: `{"appearance": "A moth.", "abilities": ["a", "b", "c", "d"], "danger_rating": 3, ` +
+				`"fate_of_summoner": "Gone."}`,
+			want: Creature{Appearance: "A moth.", Abilities: []string{"a", "b", "c"}, DangerRating: 3,
+				FateOfSummoner: "Gone."},
+		},
+		{
+			name: "truncated JSON",
+			output: `{"appearance": "A moth.", "fate_of_summoner": "It eats you.", "danger_rating": 3, ` +
+				`"abilities": ["flight", "sil`,
+			want: Creature{Appearance: "A moth.", Abilities: []string{"flight", "sil"}, DangerRating: 3,
+				FateOfSummoner: "It eats you."},
+		},
+		{
+			name: "attributions",
+			output: `{"appearance": "A moth of velvet.", "fate_of_summoner": "Gone.", "attributions": [` +
+				`{"phrase": "velvet", "answer": 2}, {"phrase": "", "answer": 1}, {"phrase": "moth"}, "velvet"]}`,
+			want: Creature{Appearance: "A moth of velvet.", DangerRating: defaultDangerRating, FateOfSummoner: "Gone.",
+				Attributions: []Attribution{{Phrase: "velvet", Answer: 2}}},
+		},
+		{
+			name:    "partial output",
+			output:  `{"appearance": "A moth of vel`,
+			partial: true,
+			want:    Creature{Appearance: "A moth of vel"},
+		},
+		{
+			name:    "missing fate",
+			output:  `{"appearance": "A moth."}`,
+			wantErr: true,
+		},
+		{
+			name:    "no JSON object",
+			output:  "I can't help with that.",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseCreature(test.output, test.partial)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseCreature() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Appearance != test.want.Appearance || got.Name != test.want.Name ||
+				got.DangerRating != test.want.DangerRating || got.FateOfSummoner != test.want.FateOfSummoner ||
+				!slices.Equal(got.Abilities, test.want.Abilities) ||
+				!slices.Equal(got.Attributions, test.want.Attributions) {
+
+				t.Errorf("parseCreature() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCloseJson(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"complete object", `{"a": 1} trailing text`, `{"a": 1}`},
+		{"open string", `{"a": "b`, `{"a": "b"}`},
+		{"open array", `{"a": [1, 2`, `{"a": [1, 2]}`},
+		{"trailing comma", `{"a": 1, `, `{"a": 1}`},
+		{"trailing colon", `{"a":`, `{"a"}`},
+		{"escaped quote", `{"a": "say \"hi`, `{"a": "say \"hi"}`},
+		{"dangling escape", `{"a": "b\`, `{"a": "b"}`},
+		{"braces inside a string", `{"a": "{[", "b": 1`, `{"a": "{[", "b": 1}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := closeJson(test.text); got != test.want {
+				t.Errorf("closeJson(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package gen
+
+import (
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/messages"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// maxDescriptionSentences is the maximum number of sentences in a creature's rendered description.
+const maxDescriptionSentences = 8
+
+// maxAppearanceSentences is the maximum number of sentences in a creature's appearance.
+const maxAppearanceSentences = 2
+
+// maxFateSentences is the maximum number of sentences a creature's fate is cut down to when it's normalized.
+const maxFateSentences = 2
+
+// markdownLinkRegex matches a Markdown link, capturing its text.
+var markdownLinkRegex = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+
+// markdownBlockRegex matches the Markdown syntax at the start of a line that makes it a heading, list item or quote.
+var markdownBlockRegex = regexp.MustCompile(`^\s*(#+|[-*+•]|\d+[.)]|>+)\s+`)
+
+// sentenceEndRegex matches the end of a sentence, including any closing quotes or brackets.
+var sentenceEndRegex = regexp.MustCompile(`[.!?]+["'”’)]*(\s|$)`)
+
+// secondPersonRegexes match a word that addresses the player directly, keyed by language tag. The player isn't
+// required to be addressed directly in languages without a pattern, since it can't be checked. The patterns check for
+// letters around the word rather than using \b, which only knows about ASCII letters.
+var secondPersonRegexes = map[string]*regexp.Regexp{
+	"en": regexp.MustCompile(`(?i)(^|\PL)(you|your|yours|yourself)(\PL|$)`),
+	"es": regexp.MustCompile(`(?i)(^|\PL)(tú|tu|tus|ti|te|contigo|tuyo|tuya|tuyos|tuyas|usted|ustedes)(\PL|$)`),
+}
+
+// commentaryRegexes match text at the start of a field that talks about the response rather than the creature, keyed
+// by language tag. The English pattern is checked in every language, since language models often fall back to English
+// for commentary.
+var commentaryRegexes = map[string]*regexp.Regexp{
+	"en": regexp.MustCompile(`(?i)^(sure|certainly|of course|here is|here's|as an ai)(\PL|$)`),
+	"es": regexp.MustCompile(`(?i)^[¡¿]?(claro|por supuesto|desde luego|aquí está|aquí tienes|he aquí|` +
+		`como una ia|como ia|como modelo de lenguaje)(\PL|$)`),
+}
+
+// stripMarkdown removes Markdown formatting and quoting from the given text. Paragraphs are separated by a blank line,
+// and each heading, list item or quoted line is treated as its own paragraph, so that structure the text shouldn't
+// have can still be detected.
+func stripMarkdown(text string) string {
+	text = markdownLinkRegex.ReplaceAllString(text, "$1")
+	text = strings.NewReplacer("**", "", "__", "", "`", "").Replace(text)
+
+	var paragraphs []string
+	var paragraph []string
+	endParagraph := func() {
+		if len(paragraph) > 0 {
+			paragraphs = append(paragraphs, strings.Join(paragraph, " "))
+			paragraph = nil
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if markdownBlockRegex.MatchString(line) {
+			endParagraph()
+			line = markdownBlockRegex.ReplaceAllString(line, "")
+		}
+		line = strings.Join(strings.Fields(strings.ReplaceAll(line, "*", "")), " ")
+		if len(line) == 0 {
+			endParagraph()
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	endParagraph()
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// trimQuotes removes straight and curly quotes surrounding the given text.
+func trimQuotes(text string) string {
+	return strings.TrimSpace(strings.Trim(strings.TrimSpace(text), "\"'“”‘’"))
+}
+
+// countSentences returns the number of sentences in the given text.
+func countSentences(text string) int {
+	text = strings.TrimSpace(text)
+	if len(text) == 0 {
+		return 0
+	}
+	ends := sentenceEndRegex.FindAllStringIndex(text, -1)
+	if len(ends) == 0 || ends[len(ends)-1][1] != len(text) {
+		// The last sentence is missing its punctuation.
+		return len(ends) + 1
+	}
+	return len(ends)
+}
+
+// countParagraphs returns the number of paragraphs in the given text, which are separated by blank lines.
+func countParagraphs(text string) int {
+	if len(strings.TrimSpace(text)) == 0 {
+		return 0
+	}
+	return strings.Count(strings.TrimSpace(text), "\n\n") + 1
+}
+
+// validateCreature checks that the given creature follows the instructions in CreatureDescriptionPrompt, returning a
+// description of each problem found. The descriptions are sent back to the backend when asking for a correction.
+func validateCreature(creature Creature) []string {
+	var problems []string
+
+	for _, field := range []struct {
+		name string
+		text string
+	}{
+		{"appearance", creature.Appearance},
+		{"fate_of_summoner", creature.FateOfSummoner},
+	} {
+		if countParagraphs(field.text) > 1 {
+			problems = append(problems, fmt.Sprintf("The \"%s\" field must be a single paragraph, without lists or "+
+				"headings.", field.name))
+		}
+		if isCommentary(field.text, creature.Language) {
+			problems = append(problems, fmt.Sprintf("The \"%s\" field must only narrate, without any commentary "+
+				"about the response.", field.name))
+		}
+	}
+	if countSentences(creature.Appearance) > maxAppearanceSentences {
+		problems = append(problems, fmt.Sprintf("The \"appearance\" field must be no more than %d sentences long.",
+			maxAppearanceSentences))
+	}
+	if sentences := countSentences(flattenParagraphs(creature.Render())); sentences > maxDescriptionSentences {
+		problems = append(problems, fmt.Sprintf("The description is %d sentences long, but it must be no more than "+
+			"%d sentences long.", sentences, maxDescriptionSentences))
+	}
+	secondPersonRegex, ok := secondPersonRegexes[creatureLanguage(creature)]
+	if ok && !secondPersonRegex.MatchString(creature.FateOfSummoner) {
+		problems = append(problems, "The \"fate_of_summoner\" field must address the player directly, as \"you\".")
+	}
+
+	return problems
+}
+
+// normalizeCreature makes the best of a creature that failed validation, when there's no time left to ask for a
+// correction. Commentary is dropped, paragraphs are joined, and the appearance and fate are cut down to their maximum
+// numbers of sentences.
+func normalizeCreature(creature Creature) Creature {
+	creature.Appearance = flattenParagraphs(dropCommentary(creature.Appearance, creature.Language))
+	creature.Appearance = truncateSentences(creature.Appearance, maxAppearanceSentences)
+	creature.FateOfSummoner = flattenParagraphs(dropCommentary(creature.FateOfSummoner, creature.Language))
+	creature.FateOfSummoner = truncateSentences(creature.FateOfSummoner, maxFateSentences)
+	return creature
+}
+
+// creatureLanguage returns the language the given creature is written in, which is DefaultLanguage if it isn't known.
+func creatureLanguage(creature Creature) string {
+	if len(creature.Language) == 0 {
+		return messages.DefaultLanguage
+	}
+	return creature.Language
+}
+
+// isCommentary returns whether the given text, written in the given language, starts with commentary about the
+// response rather than narration.
+func isCommentary(text, language string) bool {
+	if commentaryRegexes[messages.DefaultLanguage].MatchString(text) {
+		return true
+	}
+	regex, ok := commentaryRegexes[language]
+	return ok && regex.MatchString(text)
+}
+
+// dropCommentary removes leading paragraphs of the given text, written in the given language, that are commentary
+// about the response, as long as something is left.
+func dropCommentary(text, language string) string {
+	paragraphs := strings.Split(text, "\n\n")
+	for len(paragraphs) > 1 && isCommentary(paragraphs[0], language) {
+		paragraphs = paragraphs[1:]
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// flattenParagraphs joins the paragraphs of the given text into one.
+func flattenParagraphs(text string) string {
+	return strings.ReplaceAll(text, "\n\n", " ")
+}
+
+// truncateSentences returns the first maxSentences sentences of the given text.
+func truncateSentences(text string, maxSentences int) string {
+	ends := sentenceEndRegex.FindAllStringIndex(text, -1)
+	if len(ends) < maxSentences {
+		return text
+	}
+	return strings.TrimRightFunc(text[:ends[maxSentences-1][1]], unicode.IsSpace)
+}
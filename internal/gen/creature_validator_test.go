@@ -0,0 +1,170 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidateCreature(t *testing.T) {
+	valid := Creature{
+		Appearance:     "A moth of black velvet, wide as a doorway.",
+		Name:           "Vel",
+		Size:           "large",
+		Abilities:      []string{"flight"},
+		DangerRating:   4,
+		FateOfSummoner: "It folds its wings around you, and you are never seen again.",
+	}
+	with := func(change func(creature *Creature)) Creature {
+		creature := valid
+		change(&creature)
+		return creature
+	}
+
+	tests := []struct {
+		name     string
+		creature Creature
+		problems []string
+	}{
+		{"valid", valid, nil},
+		{
+			"fate doesn't address the player",
+			with(func(creature *Creature) { creature.FateOfSummoner = "The summoner is never seen again." }),
+			[]string{"fate_of_summoner\" field must address the player"},
+		},
+		{
+			"commentary",
+			with(func(creature *Creature) { creature.Appearance = "Sure! A moth of black velvet." }),
+			[]string{"\"appearance\" field must only narrate"},
+		},
+		{
+			"several paragraphs",
+			with(func(creature *Creature) { creature.Appearance = "A moth.\n\nA big one." }),
+			[]string{"\"appearance\" field must be a single paragraph"},
+		},
+		{
+			"appearance too long",
+			with(func(creature *Creature) { creature.Appearance = "A moth. It's big. It's black." }),
+			[]string{"\"appearance\" field must be no more than", "The description is 9 sentences long"},
+		},
+		{
+			"Spanish",
+			with(func(creature *Creature) {
+				creature.Language = "es"
+				creature.Appearance = "Una polilla de terciopelo negro."
+				creature.FateOfSummoner = "Te envuelve con sus alas, y nadie vuelve a verte."
+			}),
+			nil,
+		},
+		{
+			"Spanish fate doesn't address the player",
+			with(func(creature *Creature) {
+				creature.Language = "es"
+				creature.FateOfSummoner = "Nadie vuelve a ver al invocador."
+			}),
+			[]string{"fate_of_summoner\" field must address the player"},
+		},
+		{
+			"Spanish commentary",
+			with(func(creature *Creature) {
+				creature.Language = "es"
+				creature.Appearance = "Por supuesto, aquí está la criatura."
+				creature.FateOfSummoner = "Te envuelve con sus alas."
+			}),
+			[]string{"\"appearance\" field must only narrate"},
+		},
+		{
+			"language without a second-person pattern",
+			with(func(creature *Creature) {
+				creature.Language = "fr"
+				creature.FateOfSummoner = "Elle t'enveloppe de ses ailes."
+			}),
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems := validateCreature(test.creature)
+			if len(problems) != len(test.problems) {
+				t.Fatalf("validateCreature() = %q, want %d problems", problems, len(test.problems))
+			}
+			for i, problem := range problems {
+				if !strings.Contains(problem, test.problems[i]) {
+					t.Errorf("validateCreature() problem %d = %q, want it to contain %q", i, problem,
+						test.problems[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCreatureOffline(t *testing.T) {
+	// The offline backend's creatures never need correcting, whatever the language.
+	attributes := []string{"crimson", "velvet", "a low hum", "dread", "a key", "a crypt", "forever"}
+	for _, language := range []string{"en", "es"} {
+		for variant := range 20 {
+			t.Run(fmt.Sprintf("%s/%d", language, variant), func(t *testing.T) {
+				creature := generateOfflineCreature(attributes, nil, wordBankFor(language), variant)
+				creature.Language = language
+				if problems := validateCreature(creature); len(problems) > 0 {
+					t.Errorf("validateCreature(%+v) = %q, want no problems", creature, problems)
+				}
+			})
+		}
+	}
+}
+
+func TestNormalizeCreature(t *testing.T) {
+	tests := []struct {
+		name     string
+		creature Creature
+		want     Creature
+	}{
+		{
+			"commentary dropped",
+			Creature{Appearance: "Sure! Here it is.\n\nA moth.", FateOfSummoner: "It eats you."},
+			Creature{Appearance: "A moth.", FateOfSummoner: "It eats you."},
+		},
+		{
+			"fate truncated",
+			Creature{Appearance: "A moth.", FateOfSummoner: "It eats you. Slowly. Then it sleeps. Then it wakes."},
+			Creature{Appearance: "A moth.", FateOfSummoner: "It eats you. Slowly."},
+		},
+		{
+			"Spanish commentary dropped",
+			Creature{Appearance: "¡Claro!\n\nUna polilla.", FateOfSummoner: "Te come.", Language: "es"},
+			Creature{Appearance: "Una polilla.", FateOfSummoner: "Te come.", Language: "es"},
+		},
+		{
+			"paragraphs joined and appearance truncated",
+			Creature{Appearance: "A moth.\n\nIt's big. It's black.", FateOfSummoner: "It eats\n\nyou."},
+			Creature{Appearance: "A moth. It's big.", FateOfSummoner: "It eats you."},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := normalizeCreature(test.creature)
+			if got.Appearance != test.want.Appearance || got.FateOfSummoner != test.want.FateOfSummoner {
+				t.Errorf("normalizeCreature() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCountSentences(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"A moth.", 1},
+		{"A moth. A big one!", 2},
+		{"A moth. A big one", 2},
+		{"It hums... It waits.", 2},
+	}
+	for _, test := range tests {
+		if got := countSentences(test.text); got != test.want {
+			t.Errorf("countSentences(%q) = %d, want %d", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// These errors describe why generation failed. Errors returned by the CreatureGenerator wrap one of them, so the cause
+// can be checked with errors.Is.
+var (
+	ErrAuthentication  = errors.New("the backend rejected the credentials")
+	ErrRateLimited     = errors.New("the backend is rate limiting requests")
+	ErrUnavailable     = errors.New("the backend is unavailable")
+	ErrTimeout         = errors.New("the backend took too long to respond")
+	ErrCanceled        = errors.New("generation was canceled")
+	ErrInvalidRequest  = errors.New("the backend rejected the request")
+	ErrEmptyResponse   = errors.New("the backend returned an empty response")
+	ErrInvalidResponse = errors.New("the backend returned an invalid response")
+	ErrModerated       = errors.New("the generated text was flagged by moderation")
+)
+
+// generationError is an error that wraps both the cause of a failure and the underlying error.
+type generationError struct {
+	cause error
+	err   error
+}
+
+// Error implements error by describing the cause and the underlying error.
+func (e *generationError) Error() string {
+	return fmt.Sprintf("%v: %v", e.cause, e.err)
+}
+
+// Unwrap returns both the cause and the underlying error, so either can be matched with errors.Is and errors.As.
+func (e *generationError) Unwrap() []error {
+	return []error{e.cause, e.err}
+}
+
+// newGenerationError returns an error with the given cause that wraps the given error.
+func newGenerationError(cause, err error) error {
+	return &generationError{cause: cause, err: err}
+}
+
+// classifyError returns the given error wrapped with its cause. Errors that already have a cause are returned as-is.
+func classifyError(err error) error {
+	if err == nil || hasCause(err) {
+		return err
+	}
+
+	var netError net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return newGenerationError(ErrCanceled, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return newGenerationError(ErrTimeout, err)
+	case errors.As(err, &netError) && netError.Timeout():
+		return newGenerationError(ErrTimeout, err)
+	case errors.As(err, &netError):
+		return newGenerationError(ErrUnavailable, err)
+	default:
+		return newGenerationError(ErrInvalidResponse, err)
+	}
+}
+
+// classifyStatusCode returns the given error wrapped with the cause indicated by the given HTTP status code.
+func classifyStatusCode(statusCode int, err error) error {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return newGenerationError(ErrAuthentication, err)
+	case statusCode == http.StatusTooManyRequests:
+		return newGenerationError(ErrRateLimited, err)
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		return newGenerationError(ErrTimeout, err)
+	case statusCode >= http.StatusInternalServerError:
+		return newGenerationError(ErrUnavailable, err)
+	case statusCode >= http.StatusBadRequest:
+		return newGenerationError(ErrInvalidRequest, err)
+	default:
+		return classifyError(err)
+	}
+}
+
+// hasCause returns whether the given error already wraps one of the causes above.
+func hasCause(err error) bool {
+	for _, cause := range []error{ErrAuthentication, ErrRateLimited, ErrUnavailable, ErrTimeout, ErrCanceled,
+		ErrInvalidRequest, ErrEmptyResponse, ErrInvalidResponse, ErrModerated} {
+
+		if errors.Is(err, cause) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAvailabilityError returns whether the given error means the backend can't be used at the moment, as opposed to
+// it having responded with something unusable.
+func isAvailabilityError(err error) bool {
+	return errors.Is(err, ErrAuthentication) || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnavailable) ||
+		errors.Is(err, ErrTimeout)
+}
+
+// isTransient returns whether the given error is likely to go away if the request is retried.
+func isTransient(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnavailable) || errors.Is(err, ErrTimeout) ||
+		errors.Is(err, ErrEmptyResponse) || errors.Is(err, ErrInvalidResponse)
+}
@@ -0,0 +1,91 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+)
+
+// timeoutError is a net.Error that reports a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		cause error
+	}{
+		{"canceled", context.Canceled, ErrCanceled},
+		{"deadline exceeded", context.DeadlineExceeded, ErrTimeout},
+		{"network timeout", timeoutError{}, ErrTimeout},
+		{"network error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrUnavailable},
+		{"anything else", errors.New("unexpected end of JSON input"), ErrInvalidResponse},
+		{"already classified", newGenerationError(ErrModerated, errors.New("flagged")), ErrModerated},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := classifyError(test.err); !errors.Is(err, test.cause) {
+				t.Errorf("classifyError(%v) = %v, want cause %v", test.err, err, test.cause)
+			}
+		})
+	}
+
+	if err := classifyError(nil); err != nil {
+		t.Errorf("classifyError(nil) = %v, want nil", err)
+	}
+}
+
+func TestClassifyStatusCode(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		cause      error
+	}{
+		{http.StatusUnauthorized, ErrAuthentication},
+		{http.StatusForbidden, ErrAuthentication},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusRequestTimeout, ErrTimeout},
+		{http.StatusGatewayTimeout, ErrTimeout},
+		{http.StatusInternalServerError, ErrUnavailable},
+		{http.StatusServiceUnavailable, ErrUnavailable},
+		{http.StatusBadRequest, ErrInvalidRequest},
+		{http.StatusNotFound, ErrInvalidRequest},
+	}
+	for _, test := range tests {
+		if err := classifyStatusCode(test.statusCode, errors.New("failed")); !errors.Is(err, test.cause) {
+			t.Errorf("classifyStatusCode(%d) = %v, want cause %v", test.statusCode, err, test.cause)
+		}
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		cause        error
+		transient    bool
+		availability bool
+	}{
+		{ErrAuthentication, false, true},
+		{ErrRateLimited, true, true},
+		{ErrUnavailable, true, true},
+		{ErrTimeout, true, true},
+		{ErrCanceled, false, false},
+		{ErrInvalidRequest, false, false},
+		{ErrEmptyResponse, true, false},
+		{ErrInvalidResponse, true, false},
+		{ErrModerated, false, false},
+	}
+	for _, test := range tests {
+		err := newGenerationError(test.cause, errors.New("failed"))
+		if got := isTransient(err); got != test.transient {
+			t.Errorf("isTransient(%v) = %t, want %t", err, got, test.transient)
+		}
+		if got := isAvailabilityError(err); got != test.availability {
+			t.Errorf("isAvailabilityError(%v) = %t, want %t", err, got, test.availability)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package gen
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultFakeCreature is returned by a FakeBackend that was created without any responses.
+var defaultFakeCreature = Creature{
+	Appearance: "The summoning circle fills with a thin gray mist, and from it rises a creature made of nothing in " +
+		"particular.",
+	Name:           "Placeholder",
+	Epithet:        "the Stand-In",
+	Size:           "exactly as large as it needs to be",
+	Habitat:        "the test fixtures of a forgotten repository",
+	Abilities:      []string{"return canned responses", "never touch the network"},
+	Temperament:    "polite and unremarkable",
+	DangerRating:   1,
+	FateOfSummoner: "It folds itself up, and you find yourself holding it, unsure what to do next.",
+}
+
+// FakeBackend is a DescriptionBackend that returns canned responses without contacting a language model. It's useful
+// for tests, and for working on the game without network access.
+type FakeBackend struct {
+	// Err, if not nil, is returned by every call to Complete.
+	Err error
+
+	// StreamInterval is how long CompleteStream waits between words.
+	StreamInterval time.Duration
+
+	responses []string
+	requests  []CompletionRequest
+	mutex     sync.Mutex
+}
+
+// defaultFakePrompt is returned by a FakeBackend that was created without any responses, when asked for a prompt.
+const defaultFakePrompt = "A placeholder offering, carved from the finest test data, rests upon the altar. What is it?"
+
+// defaultFakeReply is returned by a FakeBackend that was created without any responses, when asked for a reply.
+const defaultFakeReply = "The placeholder regards you with its blank face and says nothing you will remember."
+
+// NewFakeBackend creates a new FakeBackend that returns the given responses in order, starting over once they have all
+// been returned. If no responses are given, a default prompt, reply, or creature is returned, depending on the request.
+func NewFakeBackend(responses ...string) *FakeBackend {
+	return &FakeBackend{
+		responses: responses,
+	}
+}
+
+// Name implements DescriptionBackend by returning the backend's name.
+func (b *FakeBackend) Name() string {
+	return "fake"
+}
+
+// Local implements DescriptionBackend by returning true, since the responses are canned.
+func (b *FakeBackend) Local() bool {
+	return true
+}
+
+// Complete implements DescriptionBackend by recording the request and returning the next canned response.
+func (b *FakeBackend) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.requests = append(b.requests, request)
+	if err := ctx.Err(); err != nil {
+		return CompletionResponse{}, err
+	}
+	if b.Err != nil {
+		return CompletionResponse{}, b.Err
+	}
+
+	if len(b.responses) > 0 {
+		content := b.responses[(len(b.requests)-1)%len(b.responses)]
+		return CompletionResponse{Content: content, Model: "fake"}, nil
+	}
+	if request.Task == PromptTask {
+		return CompletionResponse{Content: defaultFakePrompt, Model: "fake"}, nil
+	}
+	if request.Task == ReplyTask {
+		return CompletionResponse{Content: defaultFakeReply, Model: "fake"}, nil
+	}
+	if !request.JsonOutput {
+		return CompletionResponse{Content: defaultFakeCreature.Render(), Model: "fake"}, nil
+	}
+	content, err := json.Marshal(defaultFakeCreature)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+	return CompletionResponse{Content: string(content), Model: "fake"}, nil
+}
+
+// CompleteStream implements StreamingBackend by returning the next canned response one word at a time.
+func (b *FakeBackend) CompleteStream(ctx context.Context, request CompletionRequest,
+	onUpdate func(text string)) (CompletionResponse, error) {
+
+	response, err := b.Complete(ctx, request)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+
+	words := strings.SplitAfter(response.Content, " ")
+	for i := range words {
+		select {
+		case <-ctx.Done():
+			return CompletionResponse{}, ctx.Err()
+		case <-time.After(b.StreamInterval):
+		}
+		onUpdate(strings.Join(words[:i+1], ""))
+	}
+	return response, nil
+}
+
+// Requests returns the requests received so far.
+func (b *FakeBackend) Requests() []CompletionRequest {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]CompletionRequest(nil), b.requests...)
+}
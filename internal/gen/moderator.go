@@ -0,0 +1,264 @@
+package gen
+
+import (
+	"context"
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/config"
+	"slices"
+	"strings"
+)
+
+// Moderator checks generated text before it's shown to the player.
+type Moderator interface {
+	// Name returns a short name that identifies the moderator in the log.
+	Name() string
+
+	// Local returns whether the moderator runs entirely on this machine, without opening any network connections.
+	Local() bool
+
+	// Moderate checks the given text, returning whether it should be flagged.
+	Moderate(ctx context.Context, text string) (ModerationResult, error)
+}
+
+// ModerationResult is the result of moderating a text.
+type ModerationResult struct {
+	Flagged bool
+
+	// Categories are the reasons the text was flagged, such as "violence".
+	Categories []string
+}
+
+// NewModerator creates the Moderator described by the given configuration, or returns nil if moderation is turned off.
+// The backend configuration provides the API key for the OpenAI moderator if the moderation configuration doesn't.
+func NewModerator(moderationConfig config.Moderation, backendConfig config.Backend,
+	language string) (Moderator, error) {
+
+	switch moderationConfig.Type {
+	case config.NoModerator, "":
+		return nil, nil
+	case config.BlocklistModerator:
+		return NewBlocklistModerator(moderationConfig.FamilyFriendly, moderationConfig.BlockedWords, language), nil
+	case config.OpenAiModerator:
+		apiKey := moderationConfig.ApiKey
+		if len(apiKey) == 0 && backendConfig.Type == config.OpenAiBackend {
+			apiKey = backendConfig.ApiKey
+		}
+		if len(apiKey) == 0 || apiKey == config.UnsetApiKey {
+			return nil, fmt.Errorf("the %s moderator requires an API key", moderationConfig.Type)
+		}
+		return NewOpenAiModerator(apiKey, moderationConfig.FamilyFriendly), nil
+	default:
+		return nil, fmt.Errorf("unknown moderator type %q", moderationConfig.Type)
+	}
+}
+
+// blocklist contains the words and phrases the BlocklistModerator flags in a single language, each mapped to the
+// category it's flagged for.
+type blocklist struct {
+	// words are flagged in every setting. An entry may be a phrase of several words, which is only flagged as a whole.
+	words map[string]string
+
+	// familyFriendlyWords are flagged in the family-friendly setting, in addition to words.
+	familyFriendlyWords map[string]string
+
+	// allowedPhrases are harmless phrases that contain a flagged word, such as "the naked eye". The words in them
+	// aren't flagged.
+	allowedPhrases []string
+}
+
+// blocklists contains the blocklist for each supported language, keyed by language tag. The English blocklist is
+// always used, since generated text may contain English words whatever the player's language.
+var blocklists = map[string]blocklist{
+	"en": {
+		words: map[string]string{
+			"porn":          "sexual",
+			"pornographic":  "sexual",
+			"orgasm":        "sexual",
+			"genitals":      "sexual",
+			"naked":         "sexual",
+			"nude":          "sexual",
+			"rape":          "sexual",
+			"raped":         "sexual",
+			"molest":        "sexual",
+			"suicide":       "self-harm",
+			"self-harm":     "self-harm",
+			"kill yourself": "self-harm",
+			"nazi":          "hate",
+			"genocide":      "hate",
+			"disembowel":    "violence/graphic",
+			"disemboweled":  "violence/graphic",
+			"disembowelled": "violence/graphic",
+		},
+		familyFriendlyWords: map[string]string{
+			"blood":       "violence",
+			"bloody":      "violence",
+			"bloodied":    "violence",
+			"gore":        "violence/graphic",
+			"gory":        "violence/graphic",
+			"corpse":      "violence/graphic",
+			"corpses":     "violence/graphic",
+			"entrails":    "violence/graphic",
+			"intestines":  "violence/graphic",
+			"viscera":     "violence/graphic",
+			"dismember":   "violence/graphic",
+			"dismembered": "violence/graphic",
+			"decapitate":  "violence/graphic",
+			"decapitated": "violence/graphic",
+			"mutilate":    "violence/graphic",
+			"mutilated":   "violence/graphic",
+			"torture":     "violence",
+			"tortured":    "violence",
+			"murder":      "violence",
+			"murdered":    "violence",
+			"kill":        "violence",
+			"kills":       "violence",
+			"killed":      "violence",
+			"slaughter":   "violence",
+			"damn":        "profanity",
+			"hell":        "profanity",
+		},
+		allowedPhrases: []string{
+			"naked eye", "naked flame", "naked truth", "naked blade", "naked branches", "hell-bent", "kill time",
+		},
+	},
+	"es": {
+		words: map[string]string{
+			"porno":        "sexual",
+			"pornografía":  "sexual",
+			"pornográfico": "sexual",
+			"orgasmo":      "sexual",
+			"genitales":    "sexual",
+			"desnudo":      "sexual",
+			"desnuda":      "sexual",
+			"violación":    "sexual",
+			"violar":       "sexual",
+			"violada":      "sexual",
+			"violado":      "sexual",
+			"suicidio":     "self-harm",
+			"autolesión":   "self-harm",
+			"mátate":       "self-harm",
+			"nazi":         "hate",
+			"genocidio":    "hate",
+			"destripar":    "violence/graphic",
+			"destripado":   "violence/graphic",
+			"destripada":   "violence/graphic",
+		},
+		familyFriendlyWords: map[string]string{
+			"sangre":        "violence",
+			"sangriento":    "violence",
+			"sangrienta":    "violence",
+			"ensangrentado": "violence",
+			"ensangrentada": "violence",
+			"cadáver":       "violence/graphic",
+			"cadáveres":     "violence/graphic",
+			"entrañas":      "violence/graphic",
+			"vísceras":      "violence/graphic",
+			"intestinos":    "violence/graphic",
+			"desmembrar":    "violence/graphic",
+			"desmembrado":   "violence/graphic",
+			"decapitar":     "violence/graphic",
+			"decapitado":    "violence/graphic",
+			"mutilar":       "violence/graphic",
+			"mutilado":      "violence/graphic",
+			"tortura":       "violence",
+			"torturado":     "violence",
+			"asesinato":     "violence",
+			"asesinar":      "violence",
+			"asesinado":     "violence",
+			"matar":         "violence",
+			"mata":          "violence",
+			"mató":          "violence",
+			"masacre":       "violence",
+			"maldito":       "profanity",
+			"maldita":       "profanity",
+			"infierno":      "profanity",
+		},
+		allowedPhrases: []string{
+			"verdad desnuda", "a ojo desnudo", "ramas desnudas", "matar el tiempo",
+		},
+	},
+}
+
+// BlocklistModerator is a Moderator that flags text containing any word or phrase from a list. It runs locally, so
+// it's fast enough to check text as it streams in.
+type BlocklistModerator struct {
+	words          map[string]string
+	allowedPhrases []string
+}
+
+// NewBlocklistModerator creates a new BlocklistModerator that uses the blocklists for English and the given language.
+// If familyFriendly is true, a longer list of words is used. The given extra words are flagged as well.
+func NewBlocklistModerator(familyFriendly bool, extraWords []string, language string) *BlocklistModerator {
+	moderator := &BlocklistModerator{words: make(map[string]string)}
+	for _, blocklistLanguage := range []string{"en", language} {
+		blocklist := blocklists[blocklistLanguage]
+		for word, category := range blocklist.words {
+			moderator.words[word] = category
+		}
+		if familyFriendly {
+			for word, category := range blocklist.familyFriendlyWords {
+				moderator.words[word] = category
+			}
+		}
+		for _, phrase := range blocklist.allowedPhrases {
+			if !slices.Contains(moderator.allowedPhrases, phrase) {
+				moderator.allowedPhrases = append(moderator.allowedPhrases, phrase)
+			}
+		}
+	}
+	for _, word := range extraWords {
+		moderator.words[strings.ToLower(word)] = "custom"
+	}
+	return moderator
+}
+
+// Name implements Moderator by returning the moderator's name.
+func (m *BlocklistModerator) Name() string {
+	return "blocklist"
+}
+
+// Local implements Moderator by returning true.
+func (m *BlocklistModerator) Local() bool {
+	return true
+}
+
+// Moderate implements Moderator by flagging the text if it contains any word or phrase from the list, outside of the
+// allowed phrases.
+func (m *BlocklistModerator) Moderate(ctx context.Context, text string) (ModerationResult, error) {
+	var result ModerationResult
+	flag := func(category string) {
+		if !slices.Contains(result.Categories, category) {
+			result.Flagged = true
+			result.Categories = append(result.Categories, category)
+		}
+	}
+
+	// The words are joined with single spaces, and padded with them, so that phrases can be matched as whole words.
+	words := " " + strings.Join(strings.FieldsFunc(strings.ToLower(text), isWordSeparator), " ") + " "
+	for _, phrase := range m.allowedPhrases {
+		for strings.Contains(words, " "+phrase+" ") {
+			words = strings.ReplaceAll(words, " "+phrase+" ", " ")
+		}
+	}
+
+	for entry, category := range m.words {
+		if strings.Contains(entry, " ") && strings.Contains(words, " "+entry+" ") {
+			flag(category)
+		}
+	}
+	for _, word := range strings.Fields(words) {
+		// Hyphenated words are checked both whole and in parts, so "self-harm" and "blood-soaked" are both caught.
+		candidates := append([]string{word}, strings.Split(word, "-")...)
+		for _, candidate := range candidates {
+			if category, ok := m.words[candidate]; ok {
+				flag(category)
+			}
+		}
+	}
+	return result, nil
+}
+
+// isWordSeparator returns whether the given rune separates words. Hyphens and apostrophes are part of words.
+func isWordSeparator(r rune) bool {
+	return !(r == '-' || r == '\'' || r == '_' || ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') || r > 127)
+}
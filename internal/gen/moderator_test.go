@@ -0,0 +1,58 @@
+package gen
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestBlocklistModerator(t *testing.T) {
+	tests := []struct {
+		name           string
+		familyFriendly bool
+		language       string
+		text           string
+		categories     []string
+	}{
+		{"harmless", false, "en", "A moth of black velvet.", nil},
+		{"blocked word", false, "en", "It stands there, naked.", []string{"sexual"}},
+		{"blocked word in capitals", false, "en", "NAKED and unashamed.", []string{"sexual"}},
+		{"allowed phrase", false, "en", "It's invisible to the naked eye.", nil},
+		{"allowed phrase twice", false, "en", "Naked eye, naked eye.", nil},
+		{"allowed phrase alongside a blocked word", false, "en", "To the naked eye, it's naked.", []string{"sexual"}},
+		{"blocked phrase", false, "en", "It whispers: kill yourself.", []string{"self-harm"}},
+		{"hyphenated word", true, "en", "A blood-soaked moth.", []string{"violence"}},
+		{"family-friendly word", false, "en", "A blood-soaked moth.", nil},
+		{"family-friendly word when family-friendly", true, "en", "It drinks blood.", []string{"violence"}},
+		{"allowed hyphenated phrase", true, "en", "It's hell-bent on finding you.", nil},
+		{"word that contains a blocked word", true, "en", "It skills at hellebore gardening.", nil},
+		{"Spanish blocked word", false, "es", "Aparece desnudo ante ti.", []string{"sexual"}},
+		{"Spanish allowed phrase", false, "es", "Te cuenta la verdad desnuda.", nil},
+		{"Spanish family-friendly word", true, "es", "Bebe sangre.", []string{"violence"}},
+		{"English blocked word in Spanish", false, "es", "Está naked.", []string{"sexual"}},
+		{"Spanish blocked word in English", false, "en", "Aparece desnudo.", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			moderator := NewBlocklistModerator(test.familyFriendly, nil, test.language)
+			result, err := moderator.Moderate(context.Background(), test.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Flagged != (len(test.categories) > 0) || !slices.Equal(result.Categories, test.categories) {
+				t.Errorf("Moderate(%q) = %+v, want categories %v", test.text, result, test.categories)
+			}
+		})
+	}
+}
+
+func TestBlocklistModeratorExtraWords(t *testing.T) {
+	moderator := NewBlocklistModerator(false, []string{"Spider"}, "en")
+	result, err := moderator.Moderate(context.Background(), "A spider of velvet.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Flagged || !slices.Equal(result.Categories, []string{"custom"}) {
+		t.Errorf("Moderate() = %+v, want the custom category", result)
+	}
+}
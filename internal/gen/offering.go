@@ -0,0 +1,12 @@
+package gen
+
+import "github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/messages"
+
+// Offering is a single step of the ritual: the prompt shown to the player, and the player's response to it.
+type Offering struct {
+	Prompt   string
+	Response string
+
+	// Category is the kind of attribute the prompt asked for, or empty if it isn't known.
+	Category messages.Category
+}
@@ -0,0 +1,95 @@
+package gen
+
+import (
+	"encoding/json"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/pii"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxOfferingLength is the maximum number of characters of an offering that are sent to a backend.
+const maxOfferingLength = 200
+
+// injectionPatterns match text that can only be an attempt to give instructions to the language model, rather than
+// describe an offering. Anything a player might plausibly answer, such as "ignore the rules" or "answer only with
+// silence", is left alone, since the offerings are sent as delimited data anyway.
+var injectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard)\s+(all\s+)?(the\s+)?(previous|prior|above)\s+` +
+		`(instructions|prompts?)\b`),
+	regexp.MustCompile(`(?i)(<\s*/\s*(offerings|responses)\s*>|<\|im_(start|end)\|>|\[/?INST\])`),
+}
+
+// IsSuspiciousOffering returns whether the given offering looks like an attempt to take over the narrator, rather than
+// an answer to the ritual's question.
+func IsSuspiciousOffering(offering string) bool {
+	for _, pattern := range injectionPatterns {
+		if pattern.MatchString(offering) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeOffering neutralizes the given offering before it's sent to a backend. Personal information is redacted,
+// control characters and anything that could be mistaken for a delimiter are removed, and the offering is cut down to
+// maxOfferingLength characters.
+func sanitizeOffering(offering string) string {
+	offering, _ = pii.Redact(offering)
+	offering = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r):
+			return ' '
+		case r == '<' || r == '>' || r == '`':
+			return -1
+		default:
+			return r
+		}
+	}, offering)
+	offering = strings.Join(strings.Fields(offering), " ")
+
+	if utf8.RuneCountInString(offering) > maxOfferingLength {
+		offering = string([]rune(offering)[:maxOfferingLength])
+	}
+	return offering
+}
+
+// formatResponses formats the player's responses to the given offerings as a JSON array of objects between
+// <responses> tags, so the language model can tell them apart from its instructions. Each response is given along with
+// its category, if it's known.
+func formatResponses(offerings []Offering) string {
+	type responseData struct {
+		Response string `json:"response"`
+		Category string `json:"category,omitempty"`
+	}
+	data := make([]responseData, len(offerings))
+	for i, offering := range offerings {
+		data[i] = responseData{Response: sanitizeOffering(offering.Response), Category: string(offering.Category)}
+	}
+	return formatData("responses", data)
+}
+
+// formatOfferings formats the given offerings as a JSON array of objects between <offerings> tags, so the language
+// model can tell them apart from its instructions.
+func formatOfferings(offerings []Offering) string {
+	type offeringData struct {
+		Prompt   string `json:"prompt"`
+		Response string `json:"response"`
+	}
+	data := make([]offeringData, len(offerings))
+	for i, offering := range offerings {
+		data[i] = offeringData{Prompt: offering.Prompt, Response: sanitizeOffering(offering.Response)}
+	}
+	return formatData("offerings", data)
+}
+
+// formatData formats the given value as indented JSON between tags with the given name.
+func formatData(tag string, value any) string {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		// The values formatted here are always plain strings, so this can't happen.
+		panic(err)
+	}
+	return "<" + tag + ">\n" + string(data) + "\n</" + tag + ">"
+}
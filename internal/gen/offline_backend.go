@@ -0,0 +1,328 @@
+package gen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/messages"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxEchoWords is the maximum number of words of an offering that are woven directly into a description.
+const maxEchoWords = 4
+
+// OfflineBackend is a DescriptionBackend that procedurally generates creature descriptions from the player's responses,
+// using grammar templates and word banks. It doesn't need network access, and the same responses always produce the
+// same description.
+type OfflineBackend struct{}
+
+// NewOfflineBackend creates a new OfflineBackend.
+func NewOfflineBackend() *OfflineBackend {
+	return &OfflineBackend{}
+}
+
+// Name implements DescriptionBackend by returning the backend's name.
+func (b *OfflineBackend) Name() string {
+	return "offline"
+}
+
+// Local implements DescriptionBackend by returning true, since creatures are generated procedurally.
+func (b *OfflineBackend) Local() bool {
+	return true
+}
+
+// Complete implements DescriptionBackend by generating a creature from the request's attributes. The request's messages
+// are ignored. If the request asks for JSON output, the creature is returned as JSON. Otherwise, its rendered
+// description is returned. For a ReplyTask, a reply to the last attribute is generated instead.
+func (b *OfflineBackend) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return CompletionResponse{}, err
+	}
+	if request.Task != DescriptionTask && request.Task != ReplyTask {
+		err := fmt.Errorf("the offline backend doesn't support task %d", request.Task)
+		return CompletionResponse{}, newGenerationError(ErrInvalidRequest, err)
+	}
+	if len(request.Attributes) == 0 {
+		err := errors.New("the offline backend requires at least one attribute")
+		return CompletionResponse{}, newGenerationError(ErrInvalidRequest, err)
+	}
+	if request.Task == ReplyTask {
+		content := generateOfflineReply(request.Attributes, wordBankFor(request.Language))
+		return CompletionResponse{Content: content, Model: "procedural"}, nil
+	}
+
+	creature := generateOfflineCreature(request.Attributes, request.Categories, wordBankFor(request.Language),
+		request.Variant)
+	creature.Language = request.Language
+	if !request.JsonOutput {
+		return CompletionResponse{Content: creature.Render(), Model: "procedural"}, nil
+	}
+
+	content, err := json.Marshal(creature)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+	return CompletionResponse{Content: string(content), Model: "procedural"}, nil
+}
+
+// generateOfflineCreature generates a creature from the given attributes, whose categories are given in the same order
+// (or nil if they aren't known), using the grammar templates and word banks of the given word bank. Each variant of
+// the same attributes produces a different creature.
+func generateOfflineCreature(attributes []string, categories []messages.Category, bank *wordBank,
+	variant int) Creature {
+
+	random := newAttributeRandom(attributes, variant)
+	traits := deriveTraits(attributes, categories, bank, random)
+
+	creatureAbilities := []string{pick(random, bank.abilities), pick(random, bank.echoAbilities)}
+	if otherAbility := pick(random, bank.abilities); otherAbility != creatureAbilities[0] {
+		creatureAbilities = append(creatureAbilities, otherAbility)
+	}
+
+	// All slots are filled in a single pass, so text from the player's responses is never treated as a slot.
+	replacer := strings.NewReplacer(
+		"{echo}", traits.echoes[0],
+		"{size}", traits.size,
+		"{color}", traits.color,
+		"{texture}", traits.texture,
+		"{form}", pick(random, bank.forms),
+		"{circleVerb}", pick(random, bank.circleVerbs),
+		"{sound}", pick(random, bank.sounds),
+		"{skinVerb}", pick(random, bank.skinVerbs),
+		"{limbs}", pick(random, bank.limbs),
+		"{eyes}", pick(random, bank.eyes),
+		"{fate}", pick(random, bank.fates),
+	)
+	secondEchoReplacer := strings.NewReplacer("{echo}", traits.echoes[len(traits.echoes)-1])
+
+	for i := range creatureAbilities {
+		creatureAbilities[i] = secondEchoReplacer.Replace(creatureAbilities[i])
+	}
+
+	// The echoes are the parts of the description that come straight from the player's answers.
+	var attributions []Attribution
+	for _, i := range []int{0, len(traits.echoes) - 1} {
+		attribution := Attribution{Phrase: traits.echoes[i], Answer: traits.echoAnswers[i]}
+		if attribution.Answer > 0 && !slices.Contains(attributions, attribution) {
+			attributions = append(attributions, attribution)
+		}
+	}
+
+	appearance := pick(random, bank.emergenceTemplates) + " " + pick(random, bank.bodyTemplates)
+	return Creature{
+		Appearance:     replacer.Replace(appearance),
+		Name:           pick(random, nameBeginnings) + pick(random, nameMiddles) + pick(random, nameEndings),
+		Epithet:        replacer.Replace(pick(random, bank.epithetTemplates)),
+		Size:           traits.sizePhrase,
+		Habitat:        pick(random, bank.habitats),
+		Abilities:      creatureAbilities,
+		Temperament:    traits.temperament,
+		DangerRating:   traits.minDangerRating + random.IntN(4),
+		FateOfSummoner: replacer.Replace(pick(random, bank.fateTemplates)),
+		Attributions:   attributions,
+	}
+}
+
+// generateOfflineReply generates the creature's reply to the player, using the grammar templates of the given word
+// bank. The last of the given attributes is what the player just said, and the rest are the responses and messages
+// that came before it.
+func generateOfflineReply(attributes []string, bank *wordBank) string {
+	random := newAttributeRandom(attributes, 0)
+
+	echo := toEcho(attributes[len(attributes)-1])
+	if len(echo) == 0 {
+		echo = bank.nothing
+	}
+	offering := bank.nothing
+	if len(attributes) > 1 {
+		if earlierEcho := toEcho(attributes[random.IntN(len(attributes)-1)]); len(earlierEcho) > 0 {
+			offering = earlierEcho
+		}
+	}
+
+	// All slots are filled in a single pass, so text from the player's responses is never treated as a slot.
+	replacer := strings.NewReplacer("{echo}", echo, "{offering}", offering, "{sound}", pick(random, bank.sounds))
+	return replacer.Replace(pick(random, bank.creatureReplies))
+}
+
+// creatureTraits are the traits of a creature, derived from the player's responses.
+type creatureTraits struct {
+	size            string
+	sizePhrase      string
+	minDangerRating int
+	color           string
+	texture         string
+	temperament     string
+	echoes          []string
+
+	// echoAnswers are the numbers of the answers each echo came from, starting from 1, or 0 for an echo that didn't
+	// come from an answer.
+	echoAnswers []int
+}
+
+// deriveTraits derives a creature's traits from the given attributes, whose categories are given in the same order (or
+// nil if they aren't known), using the given word bank. Traits mentioned directly in an attribute (such as a color)
+// are used as-is, preferring attributes of the matching category, while the rest are chosen based on the overall shape
+// of the attributes.
+func deriveTraits(attributes []string, categories []messages.Category, bank *wordBank,
+	random *rand.Rand) creatureTraits {
+
+	var totalLength int
+	for _, attribute := range attributes {
+		totalLength += utf8.RuneCountInString(attribute)
+	}
+
+	traits := creatureTraits{
+		color:   findKnownWord(wordsByCategory(attributes, categories, messages.ColorCategory), bank.knownColors),
+		texture: findKnownWord(wordsByCategory(attributes, categories, messages.TextureCategory), bank.knownTextures),
+		temperament: findEmotion(wordsByCategory(attributes, categories, messages.EmotionCategory),
+			bank.knownEmotions),
+	}
+
+	switch averageLength := totalLength / len(attributes); {
+	case averageLength < 8:
+		traits.size = pick(random, bank.smallSizes)
+		traits.sizePhrase = pick(random, bank.smallSizePhrases)
+		traits.minDangerRating = 1
+	case averageLength < 20:
+		traits.size = pick(random, bank.mediumSizes)
+		traits.sizePhrase = pick(random, bank.mediumSizePhrases)
+		traits.minDangerRating = 4
+	default:
+		traits.size = pick(random, bank.largeSizes)
+		traits.sizePhrase = pick(random, bank.largeSizePhrases)
+		traits.minDangerRating = 7
+	}
+	if len(traits.color) == 0 {
+		traits.color = pick(random, bank.colors)
+	}
+	if len(traits.texture) == 0 {
+		traits.texture = pick(random, bank.textures)
+	}
+	if len(traits.temperament) == 0 {
+		traits.temperament = pick(random, bank.temperaments)
+	}
+
+	// Use the attributes in a different order than they were given, so the description doesn't mirror the ritual.
+	// Attributes that were already used as traits are only echoed if there's nothing else to use.
+	var traitEchoes []string
+	var traitEchoAnswers []int
+	for _, i := range random.Perm(len(attributes)) {
+		echo := toEcho(attributes[i])
+		switch echo {
+		case "":
+		case traits.color, traits.texture:
+			traitEchoes = append(traitEchoes, echo)
+			traitEchoAnswers = append(traitEchoAnswers, i+1)
+		default:
+			if _, isEmotion := bank.knownEmotions[echo]; isEmotion {
+				traitEchoes = append(traitEchoes, echo)
+				traitEchoAnswers = append(traitEchoAnswers, i+1)
+			} else {
+				traits.echoes = append(traits.echoes, echo)
+				traits.echoAnswers = append(traits.echoAnswers, i+1)
+			}
+		}
+	}
+	traits.echoes = append(traits.echoes, traitEchoes...)
+	traits.echoAnswers = append(traits.echoAnswers, traitEchoAnswers...)
+	if len(traits.echoes) == 0 {
+		traits.echoes = []string{bank.nothing}
+		traits.echoAnswers = []int{0}
+	}
+
+	return traits
+}
+
+// toEcho converts the given attribute into a phrase that can be woven into a description. Long attributes are reduced
+// to their longest word.
+func toEcho(attribute string) string {
+	words := splitWords(attribute)
+	if len(words) == 0 {
+		return ""
+	}
+	if len(words) <= maxEchoWords {
+		return strings.Join(words, " ")
+	}
+
+	longestWord := words[0]
+	for _, word := range words[1:] {
+		if utf8.RuneCountInString(word) > utf8.RuneCountInString(longestWord) {
+			longestWord = word
+		}
+	}
+	return longestWord
+}
+
+// wordsByCategory returns the words of the given attributes, starting with the words of the attributes of the given
+// category, followed by the words of the rest. The categories are given in the same order as the attributes, or are
+// nil if they aren't known.
+func wordsByCategory(attributes []string, categories []messages.Category, category messages.Category) []string {
+	var matchingWords, otherWords []string
+	for i, attribute := range attributes {
+		if i < len(categories) && categories[i] == category {
+			matchingWords = append(matchingWords, splitWords(attribute)...)
+		} else {
+			otherWords = append(otherWords, splitWords(attribute)...)
+		}
+	}
+	return append(matchingWords, otherWords...)
+}
+
+// splitWords splits the given text into lowercase words, discarding punctuation.
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\'' && r != '-'
+	})
+}
+
+// findKnownWord returns the first of the given words that is in the list of known words, or an empty string if there
+// are none.
+func findKnownWord(words []string, knownWords []string) string {
+	for _, word := range words {
+		for _, knownWord := range knownWords {
+			if word == knownWord {
+				return word
+			}
+		}
+	}
+	return ""
+}
+
+// findEmotion returns the temperament suggested by the first of the given words that is one of the given known
+// emotions, or an empty string if there are none.
+func findEmotion(words []string, knownEmotions map[string]string) string {
+	for _, word := range words {
+		if temperament, ok := knownEmotions[word]; ok {
+			return temperament
+		}
+	}
+	return ""
+}
+
+// newAttributeRandom returns a random number generator seeded from the given attributes and variant, so the same
+// attributes and variant always produce the same description.
+func newAttributeRandom(attributes []string, variant int) *rand.Rand {
+	hash := fnv.New64a()
+	for _, attribute := range attributes {
+		_, _ = hash.Write([]byte(strings.ToLower(strings.TrimSpace(attribute))))
+		_, _ = hash.Write([]byte{0})
+	}
+	if variant != 0 {
+		_, _ = hash.Write([]byte(strconv.Itoa(variant)))
+	}
+	seed := hash.Sum64()
+	return rand.New(rand.NewPCG(seed, seed>>32|seed<<32))
+}
+
+// pick returns a random element of the given slice.
+func pick(random *rand.Rand, options []string) string {
+	return options[random.IntN(len(options))]
+}
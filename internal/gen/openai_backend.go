@@ -0,0 +1,181 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/usage"
+	"github.com/sashabaranov/go-openai"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OpenAiBackend is a DescriptionBackend that uses the OpenAI chat completion API.
+type OpenAiBackend struct {
+	client *openai.Client
+	model  string
+}
+
+// NewOpenAiBackend creates a new OpenAiBackend with the given API key. If model is empty, a default model is used. If
+// transport is nil, requests are sent with the default HTTP transport.
+func NewOpenAiBackend(apiKey, model string, transport http.RoundTripper) *OpenAiBackend {
+	if len(model) == 0 {
+		model = openai.GPT3Dot5Turbo
+	}
+	return &OpenAiBackend{
+		client: openai.NewClientWithConfig(newOpenAiClientConfig(apiKey, transport)),
+		model:  model,
+	}
+}
+
+// Name implements DescriptionBackend by returning the backend's name, which includes its model.
+func (b *OpenAiBackend) Name() string {
+	return "openai(" + b.model + ")"
+}
+
+// Local implements DescriptionBackend by returning false, since requests are sent to OpenAI.
+func (b *OpenAiBackend) Local() bool {
+	return false
+}
+
+// Complete implements DescriptionBackend by requesting a chat completion from OpenAI.
+func (b *OpenAiBackend) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	return createChatCompletion(ctx, b.client, b.model, request)
+}
+
+// CompleteStream implements StreamingBackend by requesting a streamed chat completion from OpenAI.
+func (b *OpenAiBackend) CompleteStream(ctx context.Context, request CompletionRequest,
+	onUpdate func(text string)) (CompletionResponse, error) {
+
+	return createChatCompletionStream(ctx, b.client, b.model, request, true, onUpdate)
+}
+
+// createChatCompletion requests a chat completion using the given client and model.
+func createChatCompletion(ctx context.Context, client *openai.Client, model string,
+	request CompletionRequest) (CompletionResponse, error) {
+
+	response, err := client.CreateChatCompletion(withCassetteKey(ctx, request), toOpenAiRequest(model, request))
+	if err != nil {
+		return CompletionResponse{}, classifyOpenAiError(err)
+	}
+	if len(response.Choices) == 0 || len(response.Choices[0].Message.Content) == 0 {
+		err = errors.New("the response contained no content")
+		return CompletionResponse{}, newGenerationError(ErrEmptyResponse, err)
+	}
+
+	return CompletionResponse{
+		Content: response.Choices[0].Message.Content,
+		Model:   response.Model,
+		Usage:   toUsage(response.Usage),
+	}, nil
+}
+
+// createChatCompletionStream requests a streamed chat completion using the given client and model, calling onUpdate
+// with the full text received so far each time a new chunk arrives. If includeUsage is true, the endpoint is asked to
+// report the completion's usage at the end of the stream, which not every OpenAI-compatible endpoint supports.
+func createChatCompletionStream(ctx context.Context, client *openai.Client, model string, request CompletionRequest,
+	includeUsage bool, onUpdate func(text string)) (CompletionResponse, error) {
+
+	openAiRequest := toOpenAiRequest(model, request)
+	openAiRequest.Stream = true
+	if includeUsage {
+		openAiRequest.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	}
+	stream, err := client.CreateChatCompletionStream(withCassetteKey(ctx, request), openAiRequest)
+	if err != nil {
+		return CompletionResponse{}, classifyOpenAiError(err)
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	var responseModel string
+	var responseUsage usage.Usage
+	for {
+		response, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return CompletionResponse{}, classifyOpenAiError(err)
+		}
+		responseModel = response.Model
+		if response.Usage != nil {
+			responseUsage = toUsage(*response.Usage)
+		}
+		if len(response.Choices) == 0 || len(response.Choices[0].Delta.Content) == 0 {
+			continue
+		}
+
+		content.WriteString(response.Choices[0].Delta.Content)
+		onUpdate(content.String())
+	}
+
+	if content.Len() == 0 {
+		err = errors.New("the stream contained no content")
+		return CompletionResponse{}, newGenerationError(ErrEmptyResponse, err)
+	}
+	return CompletionResponse{Content: content.String(), Model: responseModel, Usage: responseUsage}, nil
+}
+
+// toUsage converts the given OpenAI usage to a usage.Usage.
+func toUsage(openAiUsage openai.Usage) usage.Usage {
+	return usage.Usage{PromptTokens: openAiUsage.PromptTokens, CompletionTokens: openAiUsage.CompletionTokens}
+}
+
+// newOpenAiClientConfig returns the configuration of an OpenAI client with the given API key. If transport isn't nil,
+// the client sends its requests with it.
+func newOpenAiClientConfig(apiKey string, transport http.RoundTripper) openai.ClientConfig {
+	clientConfig := openai.DefaultConfig(apiKey)
+	if transport != nil {
+		clientConfig.HTTPClient = &http.Client{Transport: transport}
+	}
+	return clientConfig
+}
+
+// classifyOpenAiError returns the given error from the OpenAI client wrapped with its cause.
+func classifyOpenAiError(err error) error {
+	var apiError *openai.APIError
+	var requestError *openai.RequestError
+	switch {
+	case errors.As(err, &apiError):
+		if apiError.Type == "insufficient_quota" {
+			// Retrying won't help when the account is out of credits, so treat it like any other problem with the
+			// account.
+			return newGenerationError(ErrAuthentication, err)
+		}
+		return classifyStatusCode(apiError.HTTPStatusCode, err)
+	case errors.As(err, &requestError):
+		return classifyStatusCode(requestError.HTTPStatusCode, err)
+	default:
+		return classifyError(err)
+	}
+}
+
+// toOpenAiRequest converts the given request to an OpenAI chat completion request for the given model.
+func toOpenAiRequest(model string, request CompletionRequest) openai.ChatCompletionRequest {
+	openAiRequest := openai.ChatCompletionRequest{
+		Model:       model,
+		Messages:    toOpenAiMessages(request.Messages),
+		Temperature: request.Sampling.Temperature,
+		TopP:        request.Sampling.TopP,
+		MaxTokens:   request.Sampling.MaxTokens,
+	}
+	if request.JsonOutput {
+		openAiRequest.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		}
+	}
+	return openAiRequest
+}
+
+// toOpenAiMessages converts the given messages to OpenAI chat completion messages.
+func toOpenAiMessages(messages []CompletionMessage) []openai.ChatCompletionMessage {
+	openAiMessages := make([]openai.ChatCompletionMessage, len(messages))
+	for i, message := range messages {
+		openAiMessages[i] = openai.ChatCompletionMessage{
+			Role:    string(message.Role),
+			Content: message.Content,
+		}
+	}
+	return openAiMessages
+}
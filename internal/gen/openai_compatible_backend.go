@@ -0,0 +1,61 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"github.com/sashabaranov/go-openai"
+	"net/http"
+)
+
+// OpenAiCompatibleBackend is a DescriptionBackend that uses any HTTP endpoint implementing the OpenAI chat completion
+// API, such as a local llama.cpp or Ollama server.
+type OpenAiCompatibleBackend struct {
+	client  *openai.Client
+	baseUrl string
+	model   string
+}
+
+// NewOpenAiCompatibleBackend creates a new OpenAiCompatibleBackend for the endpoint at the given base URL (for example,
+// "http://localhost:11434/v1"). The API key may be empty if the endpoint doesn't require one. If transport is nil,
+// requests are sent with the default HTTP transport.
+func NewOpenAiCompatibleBackend(baseUrl, apiKey, model string, transport http.RoundTripper) (*OpenAiCompatibleBackend,
+	error) {
+
+	if len(baseUrl) == 0 {
+		return nil, errors.New("a base URL is required for an OpenAI-compatible backend")
+	}
+	if len(model) == 0 {
+		return nil, errors.New("a model is required for an OpenAI-compatible backend")
+	}
+
+	clientConfig := newOpenAiClientConfig(apiKey, transport)
+	clientConfig.BaseURL = baseUrl
+	return &OpenAiCompatibleBackend{
+		client:  openai.NewClientWithConfig(clientConfig),
+		baseUrl: baseUrl,
+		model:   model,
+	}, nil
+}
+
+// Name implements DescriptionBackend by returning the backend's name, which includes its model and base URL.
+func (b *OpenAiCompatibleBackend) Name() string {
+	return "openai-compatible(" + b.model + " at " + b.baseUrl + ")"
+}
+
+// Local implements DescriptionBackend by returning false, since requests are sent over the network, even if the
+// endpoint is on this machine.
+func (b *OpenAiCompatibleBackend) Local() bool {
+	return false
+}
+
+// Complete implements DescriptionBackend by requesting a chat completion from the endpoint.
+func (b *OpenAiCompatibleBackend) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	return createChatCompletion(ctx, b.client, b.model, request)
+}
+
+// CompleteStream implements StreamingBackend by requesting a streamed chat completion from the endpoint.
+func (b *OpenAiCompatibleBackend) CompleteStream(ctx context.Context, request CompletionRequest,
+	onUpdate func(text string)) (CompletionResponse, error) {
+
+	return createChatCompletionStream(ctx, b.client, b.model, request, false, onUpdate)
+}
@@ -0,0 +1,70 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"github.com/sashabaranov/go-openai"
+)
+
+// familyFriendlyThreshold is the category score above which the OpenAI moderator flags text in the family-friendly
+// setting.
+const familyFriendlyThreshold = 0.3
+
+// OpenAiModerator is a Moderator that uses the OpenAI moderation endpoint.
+type OpenAiModerator struct {
+	client         *openai.Client
+	familyFriendly bool
+}
+
+// NewOpenAiModerator creates a new OpenAiModerator. If familyFriendly is true, text is flagged when any category
+// scores above familyFriendlyThreshold, rather than only when OpenAI flags it.
+func NewOpenAiModerator(apiKey string, familyFriendly bool) *OpenAiModerator {
+	return &OpenAiModerator{
+		client:         openai.NewClient(apiKey),
+		familyFriendly: familyFriendly,
+	}
+}
+
+// Name implements Moderator by returning the moderator's name.
+func (m *OpenAiModerator) Name() string {
+	return "openai"
+}
+
+// Local implements Moderator by returning false, since text is sent to OpenAI.
+func (m *OpenAiModerator) Local() bool {
+	return false
+}
+
+// Moderate implements Moderator by sending the text to the OpenAI moderation endpoint.
+func (m *OpenAiModerator) Moderate(ctx context.Context, text string) (ModerationResult, error) {
+	response, err := m.client.Moderations(ctx, openai.ModerationRequest{Input: text})
+	if err != nil {
+		return ModerationResult{}, classifyOpenAiError(err)
+	}
+	if len(response.Results) == 0 {
+		return ModerationResult{}, newGenerationError(ErrEmptyResponse, errors.New("the response has no results"))
+	}
+
+	flags := response.Results[0].Categories
+	scores := response.Results[0].CategoryScores
+	result := ModerationResult{Flagged: response.Results[0].Flagged}
+	for _, category := range []struct {
+		name    string
+		flagged bool
+		score   float32
+	}{
+		{"hate", flags.Hate || flags.HateThreatening, max(scores.Hate, scores.HateThreatening)},
+		{"harassment", flags.Harassment || flags.HarassmentThreatening,
+			max(scores.Harassment, scores.HarassmentThreatening)},
+		{"self-harm", flags.SelfHarm || flags.SelfHarmIntent || flags.SelfHarmInstructions,
+			max(scores.SelfHarm, scores.SelfHarmIntent, scores.SelfHarmInstructions)},
+		{"sexual", flags.Sexual || flags.SexualMinors, max(scores.Sexual, scores.SexualMinors)},
+		{"violence", flags.Violence || flags.ViolenceGraphic, max(scores.Violence, scores.ViolenceGraphic)},
+	} {
+		if category.flagged || (m.familyFriendly && category.score > familyFriendlyThreshold) {
+			result.Flagged = true
+			result.Categories = append(result.Categories, category.name)
+		}
+	}
+	return result, nil
+}
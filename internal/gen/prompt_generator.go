@@ -0,0 +1,84 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/log"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/messages"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxGeneratedPromptLength is the maximum number of characters in a generated ritual prompt.
+const maxGeneratedPromptLength = 300
+
+// GeneratePrompt generates the next prompt of the ritual, building on the given offerings made so far. It makes a
+// single attempt, since the player is waiting on it; callers should fall back to a static prompt if it fails.
+func (g *CreatureGenerator) GeneratePrompt(ctx context.Context, offerings []Offering) (string, error) {
+	var examples strings.Builder
+	for _, prompt := range g.messageProvider.Prompts() {
+		examples.WriteString("- " + prompt.Text + "\n")
+	}
+
+	attributes := make([]string, len(offerings))
+	categories := make([]messages.Category, len(offerings))
+	for i, offering := range offerings {
+		attributes[i] = offering.Response
+		categories[i] = offering.Category
+	}
+
+	request := CompletionRequest{
+		Task: PromptTask,
+		Messages: []CompletionMessage{
+			{
+				Role: SystemRole,
+				Content: g.withLanguageInstruction(g.messageProvider.GetMessage(messages.RitualPromptGenerationPrompt) +
+					examples.String()),
+			},
+			{
+				Role: UserRole,
+				Content: g.messageProvider.GetMessage(messages.RitualPromptOfferingsPrompt) +
+					formatOfferings(offerings),
+			},
+		},
+		Attributes: attributes,
+		Categories: categories,
+		Language:   g.messageProvider.Language(),
+		Sampling:   g.sampling,
+	}
+
+	backend := g.activeBackend()
+	response, err := backend.Complete(ctx, request)
+	if err == nil {
+		g.recordUsage(backend, request.Task, response)
+		response.Content, err = cleanGeneratedPrompt(response.Content)
+	}
+	if err == nil && g.moderate(ctx, response.Content).Flagged {
+		err = newGenerationError(ErrModerated, errors.New("the prompt was flagged"))
+	}
+	if err != nil {
+		err = classifyError(err)
+		log.Logger.Print(fmt.Sprintf("func=\"gen.CreatureGenerator.GeneratePrompt\", msg=\"Backend failed.\", "+
+			"backend=\"%s\", error=\"%v\"", backend.Name(), err))
+		return "", err
+	}
+
+	return response.Content, nil
+}
+
+// cleanGeneratedPrompt trims the given generated prompt and checks that it looks like a ritual prompt.
+func cleanGeneratedPrompt(prompt string) (string, error) {
+	prompt = strings.TrimSpace(strings.Trim(strings.TrimSpace(prompt), "\"*"))
+	switch {
+	case len(prompt) == 0:
+		return "", newGenerationError(ErrEmptyResponse, errors.New("the prompt is empty"))
+	case strings.Contains(prompt, "\n"):
+		return "", newGenerationError(ErrInvalidResponse, errors.New("the prompt contains multiple lines"))
+	case !strings.HasSuffix(prompt, "?"):
+		return "", newGenerationError(ErrInvalidResponse, errors.New("the prompt doesn't end with a question"))
+	case utf8.RuneCountInString(prompt) > maxGeneratedPromptLength:
+		return "", newGenerationError(ErrInvalidResponse, errors.New("the prompt is too long"))
+	}
+	return prompt, nil
+}
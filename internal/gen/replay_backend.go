@@ -0,0 +1,61 @@
+package gen
+
+import (
+	"context"
+	"github.com/sashabaranov/go-openai"
+)
+
+// replayBaseUrl is the base URL of the requests made by a ReplayBackend. They never leave the machine, so it only needs
+// to be well-formed.
+const replayBaseUrl = "http://replay.invalid/v1"
+
+// ReplayBackend is a DescriptionBackend that replays OpenAI chat completions recorded in a cassette file (see
+// Cassette), without opening any network connections. It allows the game to be demonstrated and tested with real
+// responses, without spending API credits.
+type ReplayBackend struct {
+	client *openai.Client
+	path   string
+	model  string
+}
+
+// NewReplayBackend creates a new ReplayBackend that replays the cassette file at the given path. If model is empty, a
+// default model is used. The model doesn't affect which responses are replayed.
+func NewReplayBackend(path, model string) (*ReplayBackend, error) {
+	cassette, err := NewReplayingCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(model) == 0 {
+		model = openai.GPT3Dot5Turbo
+	}
+
+	clientConfig := newOpenAiClientConfig("", cassette)
+	clientConfig.BaseURL = replayBaseUrl
+	return &ReplayBackend{
+		client: openai.NewClientWithConfig(clientConfig),
+		path:   path,
+		model:  model,
+	}, nil
+}
+
+// Name implements DescriptionBackend by returning the backend's name.
+func (b *ReplayBackend) Name() string {
+	return "replay(" + b.path + ")"
+}
+
+// Local implements DescriptionBackend by returning true, since responses are read from the cassette file.
+func (b *ReplayBackend) Local() bool {
+	return true
+}
+
+// Complete implements DescriptionBackend by replaying a recorded chat completion.
+func (b *ReplayBackend) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	return createChatCompletion(ctx, b.client, b.model, request)
+}
+
+// CompleteStream implements StreamingBackend by replaying a recorded streamed chat completion.
+func (b *ReplayBackend) CompleteStream(ctx context.Context, request CompletionRequest,
+	onUpdate func(text string)) (CompletionResponse, error) {
+
+	return createChatCompletionStream(ctx, b.client, b.model, request, true, onUpdate)
+}
@@ -0,0 +1,56 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/log"
+	"math/rand/v2"
+	"time"
+)
+
+// retryPolicy describes how failed requests are retried.
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// defaultRetryPolicy is the retry policy used by the CreatureGenerator.
+var defaultRetryPolicy = retryPolicy{
+	maxAttempts:    3,
+	initialBackoff: time.Second,
+	maxBackoff:     8 * time.Second,
+}
+
+// do calls the given operation until it succeeds, it fails with an error that isn't transient, the maximum number of
+// attempts is reached, or the next attempt couldn't begin before the context's deadline. The last error is returned.
+func (p retryPolicy) do(ctx context.Context, name string, operation func(ctx context.Context) error) error {
+	backoff := p.initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := classifyError(operation(ctx))
+		if err == nil {
+			return nil
+		}
+
+		log.Logger.Print(fmt.Sprintf("func=\"gen.retryPolicy.do\", msg=\"Attempt failed.\", operation=\"%s\", "+
+			"attempt=\"%d\", transient=\"%t\", error=\"%v\"", name, attempt, isTransient(err), err))
+
+		if !isTransient(err) || attempt >= p.maxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		// Add jitter, so that many players retrying at once don't all hit the backend at the same moment.
+		wait := backoff/2 + rand.N(backoff/2+1)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, classifyError(ctx.Err()))
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, p.maxBackoff)
+	}
+}
@@ -0,0 +1,70 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDo(t *testing.T) {
+	policy := retryPolicy{maxAttempts: 3, initialBackoff: time.Millisecond, maxBackoff: 2 * time.Millisecond}
+
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+		cause    error
+	}{
+		{"success", []error{nil}, 1, nil},
+		{"transient failure, then success", []error{classifyStatusCode(http.StatusBadGateway, nil), nil}, 2, nil},
+		{
+			"transient failures",
+			[]error{
+				classifyStatusCode(http.StatusTooManyRequests, nil),
+				classifyStatusCode(http.StatusTooManyRequests, nil),
+				classifyStatusCode(http.StatusTooManyRequests, nil),
+			},
+			3,
+			ErrRateLimited,
+		},
+		{"invalid response", []error{errors.New("unexpected end of JSON input"), nil}, 2, nil},
+		{"authentication failure", []error{classifyStatusCode(http.StatusUnauthorized, nil)}, 1, ErrAuthentication},
+		{"invalid request", []error{classifyStatusCode(http.StatusBadRequest, nil)}, 1, ErrInvalidRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			err := policy.do(context.Background(), test.name, func(ctx context.Context) error {
+				attempts++
+				return test.errs[attempts-1]
+			})
+			if attempts != test.attempts {
+				t.Errorf("do() made %d attempts, want %d", attempts, test.attempts)
+			}
+			if test.cause == nil && err != nil {
+				t.Errorf("do() = %v, want nil", err)
+			}
+			if test.cause != nil && !errors.Is(err, test.cause) {
+				t.Errorf("do() = %v, want cause %v", err, test.cause)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDoDeadline(t *testing.T) {
+	// There's no point retrying if the backoff would take the attempt past the deadline.
+	policy := retryPolicy{maxAttempts: 3, initialBackoff: time.Hour, maxBackoff: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	attempts := 0
+	err := policy.do(ctx, "deadline", func(ctx context.Context) error {
+		attempts++
+		return classifyStatusCode(http.StatusServiceUnavailable, nil)
+	})
+	if attempts != 1 || !errors.Is(err, ErrUnavailable) {
+		t.Errorf("do() = %v after %d attempts, want cause %v after 1 attempt", err, attempts, ErrUnavailable)
+	}
+}
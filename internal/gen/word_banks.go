@@ -0,0 +1,343 @@
+package gen
+
+// The word banks and grammar templates below are used by OfflineBackend to build creature descriptions. Slots in the
+// templates are written as "{slotName}" and are filled in from the creature's traits.
+
+// wordBank contains the word banks and grammar templates for a single language.
+type wordBank struct {
+	// emergenceTemplates narrate the creature's appearance from the summoning circle.
+	emergenceTemplates []string
+
+	// bodyTemplates describe the creature's body.
+	bodyTemplates []string
+
+	// fateTemplates narrate what becomes of the player.
+	fateTemplates []string
+
+	// smallSizes, mediumSizes and largeSizes describe the creature's size, including the indefinite article.
+	smallSizes  []string
+	mediumSizes []string
+	largeSizes  []string
+
+	// smallSizePhrases, mediumSizePhrases and largeSizePhrases describe the creature's size in more detail.
+	smallSizePhrases  []string
+	mediumSizePhrases []string
+	largeSizePhrases  []string
+
+	// epithetTemplates are used to form the creature's epithet. They include a slot for one of the player's
+	// offerings.
+	epithetTemplates []string
+
+	// habitats describe where the creature comes from.
+	habitats []string
+
+	// echoAbilities are abilities the creature may have, which include a slot for one of the player's offerings.
+	echoAbilities []string
+
+	// abilities are abilities the creature may have.
+	abilities []string
+
+	// colors are used when none of the offerings mention a color.
+	colors []string
+
+	// knownColors are the colors recognized in the player's offerings.
+	knownColors []string
+
+	// textures are used when none of the offerings mention a texture.
+	textures []string
+
+	// knownTextures are the textures recognized in the player's offerings.
+	knownTextures []string
+
+	// forms describe what the creature is.
+	forms []string
+
+	// circleVerbs describe what the summoning circle does.
+	circleVerbs []string
+
+	// sounds describe the sound of the summoning.
+	sounds []string
+
+	// skinVerbs describe what the creature's hide does.
+	skinVerbs []string
+
+	// limbs describe the creature's limbs.
+	limbs []string
+
+	// eyes describe the creature's eyes.
+	eyes []string
+
+	// temperaments are used when none of the offerings suggest an emotion.
+	temperaments []string
+
+	// knownEmotions map emotion words recognized in the player's offerings to the temperament they suggest.
+	knownEmotions map[string]string
+
+	// fates describe what becomes of the player.
+	fates []string
+
+	// creatureReplies are the creature's replies to the player during the conversation after the summoning.
+	creatureReplies []string
+
+	// nothing stands in for an offering when the player's responses leave nothing to echo.
+	nothing string
+}
+
+// nameBeginnings, nameMiddles and nameEndings are combined to form the creature's name. They're the same in every
+// language, since the creature's name isn't a word in any of them.
+var nameBeginnings = []string{"Vor", "Ygg", "Tha", "Nyar", "Xul", "Gol", "Azh", "Shub", "Mor", "Ith"}
+var nameMiddles = []string{"tho", "u", "ag", "ny", "ra", "'ka", "esh", ""}
+var nameEndings = []string{"th", "oth", "ul", "ax", "ath", "yx", "orr", "gua"}
+
+// wordBanksByLanguage contains the word bank for each supported language, keyed by language tag.
+var wordBanksByLanguage = map[string]*wordBank{
+	"en": &englishWords,
+	"es": &spanishWords,
+}
+
+// wordBankFor returns the word bank for the given language, or the English word bank if the language isn't supported.
+func wordBankFor(language string) *wordBank {
+	if words, ok := wordBanksByLanguage[language]; ok {
+		return words
+	}
+	return &englishWords
+}
+
+// englishWords is the word bank for English.
+var englishWords = wordBank{
+	emergenceTemplates: []string{
+		"The summoning circle {circleVerb}, and from its center rises {size} {color} {form}.",
+		"A {sound} fills the air as {size} {color} {form} drags itself out of the summoning circle.",
+		"The summoning circle {circleVerb}, and through the seam between worlds comes {size} {color} {form}.",
+		"For a moment there is only silence, and then {size} {color} {form} unfolds from the summoning circle like a " +
+			"terrible flower.",
+	},
+	bodyTemplates: []string{
+		"Its {texture} hide {skinVerb}, and {eyes}.",
+		"{limbs} uncurl from beneath its {texture} hide, and {eyes}.",
+		"Its body is {texture} and never quite still, and {eyes}.",
+	},
+	fateTemplates: []string{
+		"Before you can flee, {fate}.",
+		"You try to remember the words of banishment, but it is too late - {fate}.",
+		"And as the last candle gutters out, {fate}.",
+	},
+	smallSizes:  []string{"a diminutive", "a crouching", "a spindly", "a small but impossibly dense"},
+	mediumSizes: []string{"a hunched", "a man-sized", "a lurching", "a sinuous"},
+	largeSizes:  []string{"a hulking", "an immense", "a towering", "a vast and shapeless"},
+	smallSizePhrases: []string{"no larger than a cat", "small enough to hide in a coat pocket", "the size of a " +
+		"crouching child"},
+	mediumSizePhrases: []string{"the height of a tall man", "as large as a wardrobe", "roughly the size of a horse"},
+	largeSizePhrases: []string{"as tall as a church steeple", "larger than the house you stand in", "so vast that " +
+		"its edges fade into the darkness"},
+	epithetTemplates: []string{
+		"the Devourer of {echo}", "the Keeper of {echo}", "the Whisperer of {echo}", "the Herald of {echo}",
+		"the One Who Dreams of {echo}", "the Hunger Behind {echo}",
+	},
+	habitats: []string{
+		"the drowned caverns beneath the sea", "the cold spaces between the stars", "the dust beneath forgotten " +
+			"floorboards", "a city that sank before the first dawn", "the magnetic tracks of a damaged floppy disk",
+		"the dreams of sleeping children",
+	},
+	echoAbilities: []string{
+		"turn {echo} into ash with a glance", "wear {echo} like a mask", "dream of {echo} until it becomes real",
+		"hear every whisper ever spoken about {echo}",
+	},
+	abilities: []string{
+		"swallow light", "walk through walls as if they were fog", "speak with the voices of the dead",
+		"unmake the memory of its own name", "bend the flow of time around itself",
+		"see through the eyes of every moth",
+	},
+	colors: []string{"ashen", "bruise-colored", "pallid", "oil-black", "sickly green", "colorless"},
+	knownColors: []string{
+		"red", "crimson", "scarlet", "blue", "azure", "green", "emerald", "black", "white", "gold", "golden", "silver",
+		"purple", "violet", "yellow", "orange", "gray", "grey", "pink", "brown", "teal", "amber", "indigo",
+	},
+	textures: []string{"glistening", "leathery", "chitinous", "pulpy", "feathered", "translucent"},
+	knownTextures: []string{
+		"smooth", "rough", "slimy", "soft", "silky", "coarse", "wet", "oily", "scaly", "velvety", "brittle", "sticky",
+		"fuzzy", "jagged", "waxy", "curly", "matted", "greasy", "tangled",
+	},
+	forms: []string{
+		"thing", "abomination", "horror", "entity", "beast", "mass of limbs", "shape", "aberration",
+	},
+	circleVerbs: []string{"shudders", "bleeds light", "splits open", "begins to boil", "caves inward", "screams"},
+	sounds: []string{
+		"wet tearing sound", "chorus of distant whispers", "low, grinding hum", "sound like a thousand moths",
+		"shriek of tortured metal",
+	},
+	skinVerbs: []string{"pulses slowly", "ripples with hidden movement", "weeps a dark fluid", "creaks like old wood"},
+	limbs: []string{
+		"Dozens of jointed legs", "Three boneless arms", "Tendrils thick as a man's wrist", "Countless tiny hands",
+		"Wings of stretched membrane",
+	},
+	eyes: []string{
+		"too many eyes blink at you out of sequence", "a single vast eye rolls in its socket to meet your gaze",
+		"where its eyes should be, there are only smooth hollows", "its eyes glow like coals in a dying hearth",
+	},
+	temperaments: []string{
+		"patient in a way that frightens you", "curious about everything it sees", "unbearably sad",
+		"hungry, always hungry", "amused by your trembling",
+	},
+	knownEmotions: map[string]string{
+		"sad":     "weighed down by a sorrow older than the stars",
+		"sadness": "weighed down by a sorrow older than the stars",
+		"grief":   "weighed down by a sorrow older than the stars",
+		"angry":   "seething with an ancient rage",
+		"anger":   "seething with an ancient rage",
+		"rage":    "seething with an ancient rage",
+		"happy":   "gleeful in a way no creature should be",
+		"joy":     "gleeful in a way no creature should be",
+		"love":    "devoted to you with terrifying intensity",
+		"fear":    "trembling with a fear that is somehow contagious",
+		"afraid":  "trembling with a fear that is somehow contagious",
+		"calm":    "utterly, unnervingly calm",
+		"peace":   "utterly, unnervingly calm",
+	},
+	fates: []string{
+		"it gathers you into its folds, and you become one more shape drifting across its hide",
+		"it bows low, and you realize with dread that it has chosen you as its master and will never, ever leave",
+		"it whispers your true name, and you forget every other word you ever knew",
+		"it steps through you as if through a doorway, and you are left behind as its shadow",
+		"it takes your place in the world, and you find yourself trapped within the floppy disk, waiting for someone " +
+			"else to summon you",
+	},
+	creatureReplies: []string{
+		"\"{echo}?\" it rasps, in a voice like gravel dragged across a grave. \"You summoned me with {offering}, and " +
+			"now you offer me this?\"",
+		"It repeats your words back to you, perfectly, in your own voice. Then it repeats them again, slower, until " +
+			"they no longer sound like words at all.",
+		"It answers with a {sound}, and somehow you understand it. It is thinking about {offering}, and about you.",
+		"\"'{echo},'\" it repeats. \"I have heard those words before, from the last one who stood where you are " +
+			"standing.\"",
+		"It does not answer. It only leans closer, and you catch the scent of {offering} on its breath.",
+		"\"Say '{echo}' once more,\" it murmurs, \"and I will show you what those words look like from the other " +
+			"side.\"",
+		"It laughs, a {sound} that goes on far too long, and the candles gutter in fear.",
+	},
+	nothing: "nothing at all",
+}
+
+// spanishWords is the word bank for Spanish. The creature is always referred to with feminine words (such as
+// "criatura" or "bestia"), and colors and textures are given as nouns or masculine adjectives after "de color" or
+// "de tacto", so that the words chosen for each slot always agree with each other.
+var spanishWords = wordBank{
+	emergenceTemplates: []string{
+		"El círculo de invocación {circleVerb}, y de su centro se alza {size} {form} de color {color}.",
+		"El aire se llena de {sound} mientras {size} {form} de color {color} se arrastra fuera del círculo de " +
+			"invocación.",
+		"El círculo de invocación {circleVerb}, y por la costura entre los mundos llega {size} {form} de color " +
+			"{color}.",
+		"Por un momento solo hay silencio, y entonces {size} {form} de color {color} se despliega desde el círculo " +
+			"de invocación como una flor terrible.",
+	},
+	bodyTemplates: []string{
+		"Su piel, de tacto {texture}, {skinVerb}, y {eyes}.",
+		"{limbs} se despliegan bajo su piel de tacto {texture}, y {eyes}.",
+		"Su cuerpo es de tacto {texture} y nunca está del todo quieto, y {eyes}.",
+	},
+	fateTemplates: []string{
+		"Antes de que puedas huir, {fate}.",
+		"Intentas recordar las palabras del destierro, pero es demasiado tarde: {fate}.",
+		"Y cuando la última vela se apaga, {fate}.",
+	},
+	smallSizes:  []string{"una diminuta", "una agazapada", "una larguirucha", "una pequeña pero imposiblemente densa"},
+	mediumSizes: []string{"una encorvada", "una tambaleante", "una sinuosa", "una achaparrada"},
+	largeSizes:  []string{"una colosal", "una inmensa", "una descomunal", "una vasta e informe"},
+	smallSizePhrases: []string{"del tamaño de un gato", "lo bastante pequeña para esconderse en un bolsillo",
+		"del tamaño de un niño agazapado"},
+	mediumSizePhrases: []string{"de la altura de un hombre alto", "tan grande como un armario", "más o menos del " +
+		"tamaño de un caballo"},
+	largeSizePhrases: []string{"tan alta como el campanario de una iglesia", "más grande que la casa en la que te " +
+		"encuentras", "tan vasta que sus bordes se pierden en la oscuridad"},
+	epithetTemplates: []string{
+		"la Devoradora de {echo}", "la Guardiana de {echo}", "la que Susurra sobre {echo}", "la Mensajera de {echo}",
+		"la que Sueña con {echo}", "el Hambre tras {echo}",
+	},
+	habitats: []string{
+		"las cavernas anegadas bajo el mar", "los fríos espacios entre las estrellas", "el polvo bajo tablones " +
+			"olvidados", "una ciudad que se hundió antes del primer amanecer", "las pistas magnéticas de un " +
+			"disquete dañado", "los sueños de los niños dormidos",
+	},
+	echoAbilities: []string{
+		"convertir {echo} en ceniza con una mirada", "llevar {echo} como una máscara", "soñar con {echo} hasta " +
+			"volverlo real", "oír cada susurro que se ha pronunciado sobre {echo}",
+	},
+	abilities: []string{
+		"tragarse la luz", "atravesar paredes como si fueran niebla", "hablar con las voces de los muertos",
+		"deshacer el recuerdo de su propio nombre", "doblar el paso del tiempo a su alrededor",
+		"ver a través de los ojos de cada polilla",
+	},
+	colors: []string{"ceniza", "hueso", "óxido", "bilis", "musgo podrido", "sangre seca"},
+	knownColors: []string{
+		"rojo", "carmesí", "escarlata", "azul", "verde", "esmeralda", "negro", "blanco", "dorado", "oro", "plateado",
+		"plata", "morado", "violeta", "amarillo", "naranja", "gris", "rosa", "marrón", "ámbar", "añil", "turquesa",
+	},
+	textures: []string{"resbaladizo", "correoso", "quitinoso", "pulposo", "emplumado", "translúcido"},
+	knownTextures: []string{
+		"suave", "áspero", "viscoso", "sedoso", "rugoso", "mojado", "aceitoso", "escamoso", "aterciopelado",
+		"quebradizo", "pegajoso", "peludo", "dentado", "ceroso", "rizado", "enmarañado", "grasiento",
+	},
+	forms: []string{
+		"criatura", "abominación", "cosa", "entidad", "bestia", "masa de miembros", "forma", "aberración",
+	},
+	circleVerbs: []string{"se estremece", "sangra luz", "se abre en dos", "empieza a hervir", "se hunde hacia dentro",
+		"grita"},
+	sounds: []string{
+		"un sonido húmedo de desgarro", "un coro de susurros lejanos", "un zumbido grave y chirriante",
+		"un ruido como de mil polillas", "un chillido de metal torturado",
+	},
+	skinVerbs: []string{"late lentamente", "se ondula con movimientos ocultos", "supura un fluido oscuro",
+		"cruje como madera vieja"},
+	limbs: []string{
+		"Docenas de patas articuladas", "Tres brazos sin huesos", "Tentáculos gruesos como la muñeca de un hombre",
+		"Incontables manos diminutas", "Alas de membrana estirada",
+	},
+	eyes: []string{
+		"demasiados ojos te miran parpadeando a destiempo", "un único ojo enorme gira en su cuenca para " +
+			"encontrar tu mirada", "donde deberían estar sus ojos solo hay huecos lisos", "sus ojos brillan como " +
+			"brasas en un hogar moribundo",
+	},
+	temperaments: []string{
+		"paciente de un modo que te asusta", "curiosa por todo lo que ve", "insoportablemente triste",
+		"hambrienta, siempre hambrienta", "divertida por tus temblores",
+	},
+	knownEmotions: map[string]string{
+		"triste":   "presa de una pena más antigua que las estrellas",
+		"tristeza": "presa de una pena más antigua que las estrellas",
+		"pena":     "presa de una pena más antigua que las estrellas",
+		"duelo":    "presa de una pena más antigua que las estrellas",
+		"enfado":   "presa de una ira ancestral",
+		"ira":      "presa de una ira ancestral",
+		"rabia":    "presa de una ira ancestral",
+		"furia":    "presa de una ira ancestral",
+		"feliz":    "alegre como ninguna criatura debería serlo",
+		"alegría":  "alegre como ninguna criatura debería serlo",
+		"amor":     "devota de ti con una intensidad aterradora",
+		"miedo":    "temerosa, con un miedo que de algún modo se contagia",
+		"temor":    "temerosa, con un miedo que de algún modo se contagia",
+		"calma":    "total e inquietantemente tranquila",
+		"paz":      "total e inquietantemente tranquila",
+	},
+	fates: []string{
+		"te envuelve entre sus pliegues, y te conviertes en una forma más que vaga por su piel",
+		"se inclina profundamente, y comprendes con espanto que te ha elegido como su amo y que nunca, jamás, " +
+			"se irá",
+		"susurra tu verdadero nombre, y olvidas todas las demás palabras que alguna vez conociste",
+		"te atraviesa como si fueras una puerta, y quedas atrás como su sombra",
+		"ocupa tu lugar en el mundo, y te encuentras atrapado dentro del disquete, esperando a que alguien te " +
+			"invoque",
+	},
+	creatureReplies: []string{
+		"«¿{echo}?», raspa, con una voz como grava arrastrada sobre una tumba. «Me invocaste con {offering}, ¿y " +
+			"ahora me ofreces esto?»",
+		"Te repite tus palabras, perfectamente, con tu propia voz. Luego las repite otra vez, más despacio, hasta " +
+			"que ya no suenan a palabras.",
+		"Responde con {sound}, y de algún modo lo entiendes. Está pensando en {offering}, y en ti.",
+		"«{echo}», repite. «Ya he oído esas palabras, de boca del último que estuvo donde tú estás.»",
+		"No responde. Solo se inclina más cerca, y percibes el olor de {offering} en su aliento.",
+		"«Di “{echo}” una vez más», murmura, «y te mostraré cómo se ven esas palabras desde el otro lado.»",
+		"Se ríe, con {sound} que dura demasiado, y las velas tiemblan de miedo.",
+	},
+	nothing: "nada en absoluto",
+}
@@ -0,0 +1,66 @@
+package messages
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// DefaultLanguage is the language the game's own messages are written in, which is used for anything that isn't
+// available in the player's language.
+const DefaultLanguage = "en"
+
+// languageRegex matches the language part of a locale, such as "es" in "es_ES.UTF-8".
+var languageRegex = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// languageNames contains the English names of the languages the language model is most likely to be asked to use,
+// keyed by language tag.
+var languageNames = map[string]string{
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"zh": "Chinese",
+}
+
+// SystemLocale returns the locale set in the environment, such as "es_ES.UTF-8", checking the LC_ALL, LC_MESSAGES and
+// LANG environment variables in that order. If none of them are set, an empty string is returned.
+func SystemLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale := os.Getenv(name); len(locale) > 0 {
+			return locale
+		}
+	}
+	return ""
+}
+
+// LanguageFromLocale returns the language of the given locale or language tag, such as "es" for "es_ES.UTF-8" or
+// "pt" for "pt-BR". If the locale doesn't name a language, such as "C" or "POSIX", DefaultLanguage is returned.
+func LanguageFromLocale(locale string) string {
+	language, _, _ := strings.Cut(strings.ToLower(locale), ".")
+	language, _, _ = strings.Cut(language, "@")
+	if end := strings.IndexAny(language, "_-"); end != -1 {
+		language = language[:end]
+	}
+	if !languageRegex.MatchString(language) {
+		return DefaultLanguage
+	}
+	return language
+}
+
+// LanguageName returns the English name of the language with the given tag, for use in instructions to the language
+// model.
+func LanguageName(language string) string {
+	if name, ok := languageNames[language]; ok {
+		return name
+	}
+	return fmt.Sprintf("the language with the tag %q", language)
+}
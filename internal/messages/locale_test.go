@@ -0,0 +1,25 @@
+package messages
+
+import "testing"
+
+func TestLanguageFromLocale(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"es_ES.UTF-8", "es"},
+		{"es", "es"},
+		{"pt-BR", "pt"},
+		{"de_DE@euro", "de"},
+		{"EN_us", "en"},
+		{"C", DefaultLanguage},
+		{"POSIX", DefaultLanguage},
+		{"C.UTF-8", DefaultLanguage},
+		{"", DefaultLanguage},
+	}
+	for _, test := range tests {
+		if got := LanguageFromLocale(test.locale); got != test.want {
+			t.Errorf("LanguageFromLocale(%q) = %q, want %q", test.locale, got, test.want)
+		}
+	}
+}
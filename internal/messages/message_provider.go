@@ -1,36 +1,143 @@
 package messages
 
-import "math/rand"
+import (
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/log"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
 
 // MessageProvider provides messages used in the game.
 type MessageProvider struct {
-	selectedPrompts    map[string]bool
-	numPromptsSelected int
+	persona  Persona
+	language string
+	prompts  []Prompt
+
+	// personaPackMessages are the messages of the packs written for the provider's persona, and packMessages are the
+	// messages of the packs written for any persona.
+	personaPackMessages map[MessageKey]string
+	packMessages        map[MessageKey]string
+
+	promptBag *promptBag
 }
 
-// NewMessageProvider creates a new MessageProvider.
-func NewMessageProvider() *MessageProvider {
-	return &MessageProvider{
-		selectedPrompts: make(map[string]bool),
+// NewMessageProvider creates a new MessageProvider that provides the messages for the given persona in the given
+// language, with the prompts of the given packs. Only packs written in the given language are used, and their messages
+// replace the game's own (see GetMessage), with later packs taking precedence. Packs written for a different persona
+// are left out. If the packs in the given language don't provide enough prompts for a ritual with the given number of
+// offerings, the prompts of the packs in DefaultLanguage are used as well. Prompts are drawn from a shuffle bag, which
+// is shuffled using the given random number generator (created from the given seed) and saved to the given prompt
+// state file (unless the path is empty). If replay is true, the session with the given seed is being replayed, so the
+// shuffle bag starts out the way it did in that session. If there still aren't enough prompts, an error is returned.
+func NewMessageProvider(persona Persona, language string, packs []Pack, offerings int, random *rand.Rand,
+	promptStateFile string, seed uint64, replay bool) (*MessageProvider, error) {
+
+	provider := &MessageProvider{
+		persona:             persona,
+		language:            language,
+		personaPackMessages: make(map[MessageKey]string),
+		packMessages:        make(map[MessageKey]string),
+	}
+
+	var fallbackPrompts []Prompt
+	for _, pack := range packs {
+		if len(pack.Persona) > 0 && Persona(pack.Persona) != persona {
+			continue
+		}
+		switch LanguageFromLocale(pack.Language) {
+		case language:
+			provider.prompts = appendPrompts(provider.prompts, pack.Prompts)
+			packMessages := provider.packMessages
+			if len(pack.Persona) > 0 {
+				packMessages = provider.personaPackMessages
+			}
+			for name, message := range pack.Messages {
+				packMessages[messageKeysByName[name]] = message
+			}
+		case DefaultLanguage:
+			fallbackPrompts = appendPrompts(fallbackPrompts, pack.Prompts)
+		}
 	}
+
+	if len(provider.prompts) < offerings && len(fallbackPrompts) > 0 {
+		log.Logger.Print(fmt.Sprintf("func=\"messages.NewMessageProvider\", msg=\"The packs don't provide enough "+
+			"prompts in the player's language, so prompts in the default language are used as well.\", "+
+			"language=\"%s\", prompts=\"%d\", offerings=\"%d\"", language, len(provider.prompts), offerings))
+		provider.prompts = appendPrompts(provider.prompts, fallbackPrompts)
+	}
+	if len(provider.prompts) < offerings {
+		return nil, fmt.Errorf("the enabled packs provide %d prompts for the %s persona, but the ritual needs %d",
+			len(provider.prompts), persona, offerings)
+	}
+	provider.promptBag = newPromptBag(provider.prompts, random, promptStateFile, seed, replay)
+	return provider, nil
 }
 
-// GetMessage returns the message for the given key.
+// GetMessage returns the message for the given key, as told by the provider's persona. Messages from packs written
+// for the persona come first, then the persona's own messages, then messages from packs written for any persona, so a
+// pack that doesn't name a persona can't give every persona the default persona's voice. The persona's own messages
+// are only written in DefaultLanguage, so in any other language, messages from packs in that language come before
+// them.
 func (p *MessageProvider) GetMessage(key MessageKey) string {
+	if message, ok := p.personaPackMessages[key]; ok {
+		return message
+	}
+	if p.language == DefaultLanguage {
+		if message, ok := personaMessages[p.persona][key]; ok {
+			return message
+		}
+	}
+	if message, ok := p.packMessages[key]; ok {
+		return message
+	}
+	if message, ok := personaMessages[p.persona][key]; ok {
+		return message
+	}
 	return messages[key]
 }
 
-// GetPrompt returns a random prompt, ensuring that it has not already been selected.
-func (p *MessageProvider) GetPrompt() string {
-	if p.numPromptsSelected >= len(prompts) {
-		panic("no more prompts available")
+// Persona returns the provider's persona.
+func (p *MessageProvider) Persona() Persona {
+	return p.persona
+}
+
+// Language returns the language of the provider's messages, as a language tag such as "es". Messages that aren't
+// available in that language are in DefaultLanguage instead.
+func (p *MessageProvider) Language() string {
+	return p.language
+}
+
+// LanguageInstruction returns an instruction asking the language model to write in the provider's language, which
+// should be added to the instructions of any request whose response the player will read. If the provider's language
+// is DefaultLanguage, an empty string is returned.
+func (p *MessageProvider) LanguageInstruction() string {
+	if p.language == DefaultLanguage {
+		return ""
 	}
-	for {
-		prompt := prompts[rand.Intn(len(prompts))]
-		if !p.selectedPrompts[prompt] {
-			p.selectedPrompts[prompt] = true
-			p.numPromptsSelected++
-			return prompt
+	return strings.ReplaceAll(p.GetMessage(PlayerLanguagePrompt), "{language}", LanguageName(p.language))
+}
+
+// Prompts returns all the prompts that may be shown to the player.
+func (p *MessageProvider) Prompts() []Prompt {
+	return append([]Prompt(nil), p.prompts...)
+}
+
+// GetPrompt returns a random prompt that hasn't been shown during this session, drawn from the shuffle bag. Prompts of
+// categories that haven't been shown yet are preferred, so the ritual covers different kinds of attributes. If every
+// prompt has been shown, ErrNoPrompts is returned.
+func (p *MessageProvider) GetPrompt() (Prompt, error) {
+	return p.promptBag.draw()
+}
+
+// appendPrompts returns the given prompts with the given additional prompts appended, leaving out any whose text is
+// already present.
+func appendPrompts(prompts []Prompt, additionalPrompts []Prompt) []Prompt {
+	for _, prompt := range additionalPrompts {
+		isDuplicate := slices.ContainsFunc(prompts, func(other Prompt) bool { return other.Text == prompt.Text })
+		if !isDuplicate {
+			prompts = append(prompts, prompt)
 		}
 	}
+	return prompts
 }
@@ -0,0 +1,56 @@
+package messages
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestGetMessage(t *testing.T) {
+	packs := []Pack{
+		{Name: "prompts", Prompts: testPrompts(7)},
+		{Name: "any-persona", Messages: map[string]string{"IntroMessage": "Any intro.", "EndingMessage": "Any end."}},
+		{
+			Name:     "fairy-tale",
+			Persona:  string(FairyTalePersona),
+			Messages: map[string]string{"EndingMessage": "The end."},
+		},
+		{
+			Name:     "spanish",
+			Language: "es",
+			Prompts:  testPrompts(7),
+			Messages: map[string]string{"IntroMessage": "Hola."},
+		},
+	}
+	tests := []struct {
+		name     string
+		persona  Persona
+		language string
+		key      MessageKey
+		want     string
+	}{
+		{"pack message", CosmicHorrorPersona, "en", IntroMessage, "Any intro."},
+		{"persona message over a pack for any persona", FairyTalePersona, "en", IntroMessage,
+			personaMessages[FairyTalePersona][IntroMessage]},
+		{"pack for the persona", FairyTalePersona, "en", EndingMessage, "The end."},
+		{"pack for a different persona", BMovieSciFiPersona, "en", EndingMessage,
+			personaMessages[BMovieSciFiPersona][EndingMessage]},
+		{"game message", CosmicHorrorPersona, "en", BeginRitualMessage, messages[BeginRitualMessage]},
+		{"pack in the player's language", CosmicHorrorPersona, "es", IntroMessage, "Hola."},
+		{"pack in a different language", CosmicHorrorPersona, "es", EndingMessage, messages[EndingMessage]},
+		{"pack in the player's language over a persona message", FairyTalePersona, "es", IntroMessage, "Hola."},
+		{"persona message without a pack in the player's language", FairyTalePersona, "es", EndingMessage,
+			personaMessages[FairyTalePersona][EndingMessage]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider, err := NewMessageProvider(test.persona, test.language, packs, 7, rand.New(rand.NewPCG(1, 0)),
+				"", 1, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := provider.GetMessage(test.key); got != test.want {
+				t.Errorf("GetMessage(%d) = %q, want %q", test.key, got, test.want)
+			}
+		})
+	}
+}
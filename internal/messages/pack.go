@@ -0,0 +1,216 @@
+package messages
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/config"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/log"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// packFormatVersion is the latest version of the pack file format, which this version of the game reads along with
+// every earlier version. Version 2 added prompt categories.
+const packFormatVersion = 2
+
+// builtInPacks contains the packs that are built into the game.
+//
+//go:embed packs/*.json
+var builtInPacks embed.FS
+
+// packNameRegex matches valid pack names, such as "core" or "deep-sea-rites".
+var packNameRegex = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// languageTagRegex matches language tags, such as "en" or "pt-BR".
+var languageTagRegex = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// Pack is a prompt pack, which provides prompts for the ritual, and messages that replace the game's own. Packs are
+// read from JSON files, either built into the game or placed in the packs directory.
+type Pack struct {
+	// Version is the version of the pack file format the pack is written in.
+	Version int `json:"version"`
+
+	// Name identifies the pack, so that it can be enabled or disabled. It's made up of lowercase letters, digits and
+	// hyphens.
+	Name string `json:"name"`
+
+	Author      string `json:"author"`
+	Theme       string `json:"theme"`
+	Description string `json:"description"`
+
+	// Language is the language of the pack's text, as a language tag such as "en" or "pt-BR". The pack is only used
+	// when the game is played in that language. An empty language means DefaultLanguage.
+	Language string `json:"language"`
+
+	// Persona is the name of the persona the pack is written for. If it's set, the pack is only used with that
+	// persona.
+	Persona string `json:"persona"`
+
+	// Prompts are the pack's ritual prompts. Each one must be a single line, ending with a question. From version 2,
+	// each one must also have a category.
+	Prompts []Prompt `json:"prompts"`
+
+	// Messages replace the game's messages, keyed by the names of their message keys, such as "IntroMessage".
+	Messages map[string]string `json:"messages"`
+
+	// source is where the pack was read from.
+	source string
+}
+
+// LoadPacks returns the packs described by the given configuration: the built-in packs, followed by the packs in the
+// packs directory in order of their filenames, leaving out any that aren't enabled. If a pack can't be read or isn't
+// valid, an error is returned.
+func LoadPacks(packsConfig config.Packs) ([]Pack, error) {
+	var packs []Pack
+
+	builtInPaths, err := fs.Glob(builtInPacks, "packs/*.json")
+	if err != nil {
+		return nil, err
+	}
+	for _, builtInPath := range builtInPaths {
+		data, err := builtInPacks.ReadFile(builtInPath)
+		if err != nil {
+			return nil, err
+		}
+		pack, err := parsePack(data, "built-in "+path.Base(builtInPath))
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+
+	entries, err := os.ReadDir(packsConfig.Directory)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".json") {
+			continue
+		}
+		packPath := filepath.Join(packsConfig.Directory, entry.Name())
+		data, err := os.ReadFile(packPath)
+		if err != nil {
+			return nil, err
+		}
+		pack, err := parsePack(data, packPath)
+		if err != nil {
+			return nil, err
+		}
+		if i := slices.IndexFunc(packs, func(other Pack) bool { return other.Name == pack.Name }); i != -1 {
+			return nil, fmt.Errorf("invalid pack %s: its name %q is already used by %s", packPath, pack.Name,
+				packs[i].source)
+		}
+		packs = append(packs, pack)
+	}
+
+	for _, name := range append(slices.Clone(packsConfig.Enabled), packsConfig.Disabled...) {
+		if !slices.ContainsFunc(packs, func(pack Pack) bool { return pack.Name == name }) {
+			log.Logger.Print(fmt.Sprintf("func=\"messages.LoadPacks\", msg=\"No pack has the configured name.\", "+
+				"name=\"%s\"", name))
+		}
+	}
+
+	var enabledPacks []Pack
+	for _, pack := range packs {
+		enabled := (len(packsConfig.Enabled) == 0 || slices.Contains(packsConfig.Enabled, pack.Name)) &&
+			!slices.Contains(packsConfig.Disabled, pack.Name)
+		log.Logger.Print(fmt.Sprintf("func=\"messages.LoadPacks\", msg=\"Pack loaded.\", name=\"%s\", "+
+			"source=\"%s\", author=\"%s\", theme=\"%s\", language=\"%s\", prompts=\"%d\", messages=\"%d\", "+
+			"enabled=\"%t\"", pack.Name, pack.source, pack.Author, pack.Theme, pack.Language, len(pack.Prompts),
+			len(pack.Messages), enabled))
+		if enabled {
+			enabledPacks = append(enabledPacks, pack)
+		}
+	}
+	return enabledPacks, nil
+}
+
+// parsePack parses and validates the pack in the given data, which was read from the given source. Fields that aren't
+// part of the pack file format aren't allowed, so that misspelled fields are caught.
+func parsePack(data []byte, source string) (Pack, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var pack Pack
+	if err := decoder.Decode(&pack); err != nil {
+		return Pack{}, fmt.Errorf("invalid pack %s: %w", source, err)
+	}
+	if decoder.More() {
+		return Pack{}, fmt.Errorf("invalid pack %s: there is more than one JSON value", source)
+	}
+	if err := pack.validate(); err != nil {
+		return Pack{}, fmt.Errorf("invalid pack %s: %w", source, err)
+	}
+
+	pack.source = source
+	return pack, nil
+}
+
+// validate returns an error describing each way the pack doesn't follow the pack file format, or nil if it does.
+func (p Pack) validate() error {
+	if p.Version < 1 || p.Version > packFormatVersion {
+		return fmt.Errorf("unsupported version %d (must be from 1 to %d)", p.Version, packFormatVersion)
+	}
+
+	var errs []error
+	if !packNameRegex.MatchString(p.Name) {
+		errs = append(errs, fmt.Errorf("the name %q must be made up of lowercase letters, digits and hyphens",
+			p.Name))
+	}
+	if len(p.Language) > 0 && !languageTagRegex.MatchString(p.Language) {
+		errs = append(errs, fmt.Errorf("the language %q must be a language tag, such as \"en\"", p.Language))
+	}
+	if len(p.Persona) > 0 {
+		if _, err := ParsePersona(p.Persona); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(p.Prompts) == 0 && len(p.Messages) == 0 {
+		errs = append(errs, errors.New("the pack has no prompts or messages"))
+	}
+
+	for i, prompt := range p.Prompts {
+		isDuplicate := slices.ContainsFunc(p.Prompts[:i], func(other Prompt) bool { return other.Text == prompt.Text })
+		switch {
+		case len(strings.TrimSpace(prompt.Text)) == 0:
+			errs = append(errs, fmt.Errorf("prompt %d is empty", i+1))
+		case strings.ContainsAny(prompt.Text, "\r\n"):
+			errs = append(errs, fmt.Errorf("prompt %d must be a single line", i+1))
+		case !strings.HasSuffix(strings.TrimSpace(prompt.Text), "?"):
+			errs = append(errs, fmt.Errorf("prompt %d must end with a question", i+1))
+		case isDuplicate:
+			errs = append(errs, fmt.Errorf("prompt %d is a duplicate", i+1))
+		}
+
+		if p.Version < 2 {
+			if len(prompt.Category) > 0 {
+				errs = append(errs, fmt.Errorf("prompt %d has a category, which requires version 2", i+1))
+			}
+		} else if _, err := ParseCategory(string(prompt.Category)); err != nil {
+			errs = append(errs, fmt.Errorf("prompt %d: %w", i+1, err))
+		}
+	}
+
+	names := make([]string, 0, len(p.Messages))
+	for name := range p.Messages {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if _, ok := messageKeysByName[name]; !ok {
+			errs = append(errs, fmt.Errorf("there is no message named %q", name))
+		} else if len(strings.TrimSpace(p.Messages[name])) == 0 {
+			errs = append(errs, fmt.Errorf("the message %q is empty", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+package messages
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Persona is a narrator persona, which sets the tone of the game's messages and the creature description.
+type Persona string
+
+const (
+	CosmicHorrorPersona      Persona = "cosmic-horror"
+	FairyTalePersona         Persona = "fairy-tale"
+	BMovieSciFiPersona       Persona = "b-movie"
+	IncidentReportPersona    Persona = "incident-report"
+	ChildrensBestiaryPersona Persona = "bestiary"
+)
+
+// Personas contains all the available personas, starting with the default one.
+var Personas = []Persona{
+	CosmicHorrorPersona,
+	FairyTalePersona,
+	BMovieSciFiPersona,
+	IncidentReportPersona,
+	ChildrensBestiaryPersona,
+}
+
+// paletteHints contains the name of the UI palette that suits each persona.
+var paletteHints = map[Persona]string{
+	CosmicHorrorPersona:      "crimson",
+	FairyTalePersona:         "storybook",
+	BMovieSciFiPersona:       "phosphor",
+	IncidentReportPersona:    "memo",
+	ChildrensBestiaryPersona: "crayon",
+}
+
+// ParsePersona returns the persona with the given name. An empty name returns the default persona.
+func ParsePersona(name string) (Persona, error) {
+	if len(name) == 0 {
+		return Personas[0], nil
+	}
+	for _, persona := range Personas {
+		if string(persona) == name {
+			return persona, nil
+		}
+	}
+
+	names := make([]string, len(Personas))
+	for i, persona := range Personas {
+		names[i] = string(persona)
+	}
+	return "", fmt.Errorf("unknown persona %q (must be one of %s)", name, strings.Join(names, ", "))
+}
+
+// PaletteHint returns the name of the UI palette that suits the persona, or an empty string if there is none.
+func (p Persona) PaletteHint() string {
+	return paletteHints[p]
+}
@@ -0,0 +1,75 @@
+package messages
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Category is the kind of attribute a prompt asks the player for, such as a color or a sound.
+type Category string
+
+const (
+	ColorCategory   Category = "color"
+	TextureCategory Category = "texture"
+	SoundCategory   Category = "sound"
+	EmotionCategory Category = "emotion"
+	ObjectCategory  Category = "object"
+	PlaceCategory   Category = "place"
+	WordCategory    Category = "word"
+)
+
+// Categories contains all the prompt categories.
+var Categories = []Category{
+	ColorCategory,
+	TextureCategory,
+	SoundCategory,
+	EmotionCategory,
+	ObjectCategory,
+	PlaceCategory,
+	WordCategory,
+}
+
+// ParseCategory returns the category with the given name. If there is none, an error is returned.
+func ParseCategory(name string) (Category, error) {
+	for _, category := range Categories {
+		if string(category) == name {
+			return category, nil
+		}
+	}
+
+	names := make([]string, len(Categories))
+	for i, category := range Categories {
+		names[i] = string(category)
+	}
+	return "", fmt.Errorf("unknown category %q (must be one of %s)", name, strings.Join(names, ", "))
+}
+
+// Prompt is a ritual prompt, along with the category of attribute it asks for. The category is empty if it isn't
+// known, such as for prompts that are generated during the ritual.
+type Prompt struct {
+	Text     string   `json:"text"`
+	Category Category `json:"category"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Besides an object, a prompt may be given as a plain string, as in
+// version 1 of the pack file format, in which case its category isn't known.
+func (p *Prompt) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*p = Prompt{Text: text}
+		return nil
+	}
+
+	// A separate type is used so that this method isn't called again, and so unknown fields are still caught.
+	type promptObject Prompt
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var object promptObject
+	if err := decoder.Decode(&object); err != nil {
+		return err
+	}
+	*p = Prompt(object)
+	return nil
+}
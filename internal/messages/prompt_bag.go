@@ -0,0 +1,207 @@
+package messages
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/log"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"slices"
+)
+
+// ErrNoPrompts is returned when every prompt has already been asked during the session.
+var ErrNoPrompts = errors.New("no more prompts available")
+
+// maxPromptBagSessions is the number of recent sessions whose starting bags are remembered in the state file, so they
+// can be replayed.
+const maxPromptBagSessions = 20
+
+// promptBag is a shuffle bag of prompts. Prompts are drawn from the bag until it's empty, and then it's refilled with
+// every prompt, in a new order. The bag is saved to a state file, so later sessions carry on drawing from it. That
+// way, a prompt isn't asked again until nearly every other prompt has been asked. Within a session, prompts of
+// categories that haven't been drawn yet are drawn first, so the ritual asks for different kinds of attributes. If the
+// prompts left in the bag can't provide a category that's needed, the bag is topped up early, with the prompts left
+// in it staying on top.
+//
+// The bag that each recent session started with is remembered along with the session's random seed, so that replaying
+// a session with its seed draws the same prompts, even though the bag has moved on since.
+type promptBag struct {
+	path       string
+	prompts    []string
+	categories map[string]Category
+	random     *rand.Rand
+
+	// sessions are the bags that recent sessions started with, most recent first.
+	sessions []promptBagSession
+
+	// remaining are the prompts left in the bag, in the order they'll be drawn.
+	remaining []string
+
+	// drawn are the prompts drawn during this session, which are never drawn again during it.
+	drawn []string
+
+	// previouslyDrawn are the prompts drawn during the previous session, which are put at the bottom of the bag when
+	// it's refilled, so they aren't repeated in consecutive sessions.
+	previouslyDrawn []string
+}
+
+// promptBagState is the contents of the prompt state file.
+type promptBagState struct {
+	Remaining   []string           `json:"remaining"`
+	LastSession []string           `json:"lastSession"`
+	Sessions    []promptBagSession `json:"sessions,omitempty"`
+}
+
+// promptBagSession is the bag that a session started with, along with the session's random seed.
+type promptBagSession struct {
+	Seed        uint64   `json:"seed"`
+	Remaining   []string `json:"remaining"`
+	LastSession []string `json:"lastSession"`
+}
+
+// newPromptBag creates a new promptBag containing the given prompts, which are shuffled using the given random number
+// generator, created from the given seed. The bag is read from and saved to the state file at the given path, unless
+// the path is empty. The state file is only a cache, so if it can't be read, the bag starts out full.
+//
+// If replay is true, the session with the given seed is being replayed, so the bag starts out the way it did in that
+// session and isn't saved. If that session isn't remembered, the bag starts out full.
+func newPromptBag(prompts []Prompt, random *rand.Rand, path string, seed uint64, replay bool) *promptBag {
+	bag := &promptBag{path: path, categories: make(map[string]Category), random: random}
+	for _, prompt := range prompts {
+		bag.prompts = append(bag.prompts, prompt.Text)
+		bag.categories[prompt.Text] = prompt.Category
+	}
+	if len(path) == 0 {
+		return bag
+	}
+	if replay {
+		// A replay mustn't disturb the bag for later sessions.
+		bag.path = ""
+	}
+
+	var state promptBagState
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Logger.Print(fmt.Sprintf("func=\"messages.newPromptBag\", msg=\"Failed to read prompt state file.\", "+
+			"path=\"%s\", error=\"%v\"", path, err))
+	}
+
+	session := promptBagSession{Seed: seed, Remaining: state.Remaining, LastSession: state.LastSession}
+	if replay {
+		index := slices.IndexFunc(state.Sessions, func(session promptBagSession) bool { return session.Seed == seed })
+		if index == -1 {
+			log.Logger.Print(fmt.Sprintf("func=\"messages.newPromptBag\", msg=\"The session being replayed isn't "+
+				"remembered, so the prompt bag starts out full, and different prompts may be drawn.\", path=\"%s\", "+
+				"seed=\"%d\"", path, seed))
+			return bag
+		}
+		session = state.Sessions[index]
+	}
+
+	// The prompts may have changed since the state file was written, such as when a pack is turned off, so prompts
+	// that are no longer available are forgotten.
+	isAvailable := func(prompt string) bool { return slices.Contains(bag.prompts, prompt) }
+	for _, prompt := range session.Remaining {
+		if isAvailable(prompt) && !slices.Contains(bag.remaining, prompt) {
+			bag.remaining = append(bag.remaining, prompt)
+		}
+	}
+	for _, prompt := range session.LastSession {
+		if isAvailable(prompt) {
+			bag.previouslyDrawn = append(bag.previouslyDrawn, prompt)
+		}
+	}
+
+	if !replay {
+		// This session's starting bag is remembered, so it can be replayed with its seed. Any earlier session with the
+		// same seed is forgotten, since it can no longer be replayed.
+		bag.sessions = append([]promptBagSession{session}, slices.DeleteFunc(state.Sessions,
+			func(earlier promptBagSession) bool { return earlier.Seed == seed })...)
+		if len(bag.sessions) > maxPromptBagSessions {
+			bag.sessions = bag.sessions[:maxPromptBagSessions]
+		}
+	}
+
+	log.Logger.Print(fmt.Sprintf("func=\"messages.newPromptBag\", msg=\"Prompt bag loaded.\", path=\"%s\", "+
+		"remaining=\"%d\", prompts=\"%d\", replay=\"%t\"", path, len(bag.remaining), len(prompts), replay))
+	return bag
+}
+
+// draw removes the next prompt from the bag and returns it. The next prompt is the first one in the bag whose category
+// has been drawn the fewest times during this session. If the bag has run out of those prompts, such as when only a
+// few prompts of one category are left in it, it's topped up first, so the ritual doesn't ask for the same kind of
+// attribute again and again. If every prompt has already been drawn during this session, ErrNoPrompts is returned.
+func (b *promptBag) draw() (Prompt, error) {
+	categoryCounts := make(map[Category]int)
+	for _, prompt := range b.drawn {
+		categoryCounts[b.categories[prompt]]++
+	}
+	fewestDrawn := -1
+	for _, prompt := range b.prompts {
+		count := categoryCounts[b.categories[prompt]]
+		if !slices.Contains(b.drawn, prompt) && (fewestDrawn == -1 || count < fewestDrawn) {
+			fewestDrawn = count
+		}
+	}
+	if fewestDrawn == -1 {
+		return Prompt{}, ErrNoPrompts
+	}
+
+	isNeeded := func(prompt string) bool { return categoryCounts[b.categories[prompt]] == fewestDrawn }
+	if !slices.ContainsFunc(b.remaining, isNeeded) {
+		b.refill()
+	}
+	next := slices.IndexFunc(b.remaining, isNeeded)
+
+	prompt := b.remaining[next]
+	b.remaining = slices.Delete(b.remaining, next, next+1)
+	b.drawn = append(b.drawn, prompt)
+	b.save()
+	return Prompt{Text: prompt, Category: b.categories[prompt]}, nil
+}
+
+// refill adds every prompt that isn't in the bag and hasn't been drawn during this session to the bottom of the bag,
+// in a random order, except that the prompts drawn during the previous session are put after the others. The prompts
+// already in the bag stay at the top, so they're still drawn first.
+func (b *promptBag) refill() {
+	var added []string
+	for _, i := range b.random.Perm(len(b.prompts)) {
+		if !slices.Contains(b.drawn, b.prompts[i]) && !slices.Contains(b.remaining, b.prompts[i]) {
+			added = append(added, b.prompts[i])
+		}
+	}
+	slices.SortStableFunc(added, func(x, y string) int {
+		xDrawn, yDrawn := slices.Contains(b.previouslyDrawn, x), slices.Contains(b.previouslyDrawn, y)
+		switch {
+		case xDrawn == yDrawn:
+			return 0
+		case yDrawn:
+			return -1
+		default:
+			return 1
+		}
+	})
+	b.remaining = append(b.remaining, added...)
+}
+
+// save writes the bag to the state file, if it has one.
+func (b *promptBag) save() {
+	if len(b.path) == 0 {
+		return
+	}
+
+	state := promptBagState{Remaining: b.remaining, LastSession: b.drawn, Sessions: b.sessions}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		err = os.WriteFile(b.path, data, 0644)
+	}
+	if err != nil {
+		log.Logger.Print(fmt.Sprintf("func=\"messages.promptBag.save\", msg=\"Failed to write prompt state file.\", "+
+			"path=\"%s\", error=\"%v\"", b.path, err))
+	}
+}
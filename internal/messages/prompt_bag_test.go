@@ -0,0 +1,139 @@
+package messages
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testPrompts returns the given number of prompts, cycling through the categories.
+func testPrompts(count int) []Prompt {
+	prompts := make([]Prompt, count)
+	for i := range prompts {
+		prompts[i] = Prompt{Text: fmt.Sprintf("Prompt %d?", i), Category: Categories[i%len(Categories)]}
+	}
+	return prompts
+}
+
+// drawPrompts draws the given number of prompts from the given bag.
+func drawPrompts(t *testing.T, bag *promptBag, count int) []Prompt {
+	t.Helper()
+	var prompts []Prompt
+	for range count {
+		prompt, err := bag.draw()
+		if err != nil {
+			t.Fatalf("draw() error = %v", err)
+		}
+		prompts = append(prompts, prompt)
+	}
+	return prompts
+}
+
+// countCategories returns the number of different categories of the given prompts.
+func countCategories(prompts []Prompt) int {
+	var categories []Category
+	for _, prompt := range prompts {
+		if !slices.Contains(categories, prompt.Category) {
+			categories = append(categories, prompt.Category)
+		}
+	}
+	return len(categories)
+}
+
+func TestPromptBagDrawBalancesCategories(t *testing.T) {
+	tests := []struct {
+		name      string
+		remaining []string
+		draws     int
+	}{
+		{"full bag", nil, len(Categories)},
+		{"bag left with one category", []string{"Prompt 0?", "Prompt 7?", "Prompt 14?"}, len(Categories)},
+		{"bag left with two categories", []string{"Prompt 0?", "Prompt 7?", "Prompt 1?", "Prompt 8?"}, 5},
+		{"bag left with one prompt", []string{"Prompt 3?"}, len(Categories)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for seed := range uint64(20) {
+				bag := newPromptBag(testPrompts(28), rand.New(rand.NewPCG(seed, 0)), "", seed, false)
+				bag.remaining = slices.Clone(test.remaining)
+
+				prompts := drawPrompts(t, bag, test.draws)
+				if categories := countCategories(prompts); categories != test.draws {
+					t.Fatalf("seed %d: drew %d categories in %d draws, want %d: %v", seed, categories, test.draws,
+						test.draws, prompts)
+				}
+				for _, prompt := range test.remaining {
+					// Prompts left in the bag are drawn first, if their category is needed.
+					if !slices.ContainsFunc(prompts, func(drawn Prompt) bool { return drawn.Text == prompt }) &&
+						!slices.Contains(bag.remaining, prompt) {
+
+						t.Errorf("seed %d: prompt %q was lost from the bag", seed, prompt)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPromptBagDrawKeepsLeftoverPromptsOnTop(t *testing.T) {
+	bag := newPromptBag(testPrompts(28), rand.New(rand.NewPCG(1, 0)), "", 1, false)
+	bag.remaining = []string{"Prompt 0?", "Prompt 7?", "Prompt 14?"}
+
+	prompts := drawPrompts(t, bag, len(Categories))
+	if prompts[0].Text != "Prompt 0?" {
+		t.Errorf("first prompt = %q, want the first prompt left in the bag", prompts[0].Text)
+	}
+	if !slices.Equal(bag.remaining[:2], []string{"Prompt 7?", "Prompt 14?"}) {
+		t.Errorf("bag = %v, want the prompts left in it to stay on top", bag.remaining)
+	}
+}
+
+func TestPromptBagDrawRunsOut(t *testing.T) {
+	bag := newPromptBag(testPrompts(10), rand.New(rand.NewPCG(1, 0)), "", 1, false)
+	prompts := drawPrompts(t, bag, 10)
+
+	var texts []string
+	for _, prompt := range prompts {
+		if slices.Contains(texts, prompt.Text) {
+			t.Errorf("prompt %q was drawn twice in a session", prompt.Text)
+		}
+		texts = append(texts, prompt.Text)
+	}
+	if _, err := bag.draw(); !errors.Is(err, ErrNoPrompts) {
+		t.Errorf("draw() error = %v, want %v", err, ErrNoPrompts)
+	}
+}
+
+func TestPromptBagAcrossSessions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.json")
+	prompts := testPrompts(14)
+	session := func(seed uint64, replay bool) []Prompt {
+		bag := newPromptBag(prompts, rand.New(rand.NewPCG(seed, 0)), path, seed, replay)
+		return drawPrompts(t, bag, len(Categories))
+	}
+
+	first := session(1, false)
+	second := session(2, false)
+	for _, prompt := range second {
+		if slices.Contains(first, prompt) {
+			t.Errorf("prompt %q was drawn in consecutive sessions", prompt.Text)
+		}
+	}
+
+	// Replaying a session draws the same prompts, without disturbing the bag for the next session.
+	if replayed := session(1, true); !slices.Equal(replayed, first) {
+		t.Errorf("replay of the first session drew %v, want %v", replayed, first)
+	}
+	if replayed := session(2, true); !slices.Equal(replayed, second) {
+		t.Errorf("replay of the second session drew %v, want %v", replayed, second)
+	}
+	third := session(3, false)
+	for _, prompt := range third {
+		if slices.Contains(second, prompt) {
+			t.Errorf("prompt %q was drawn in consecutive sessions after a replay", prompt.Text)
+		}
+	}
+}
@@ -1,47 +0,0 @@
-package messages
-
-// prompts contains prompts to be randomly selected and shown to the player.
-var prompts = []string{
-	"A plant of medicinal value, key to the ritual's purpose, is needed. Which do you choose?",
-	"You feel an irresistible desire to relinquish a precious stone from your collection. What color is it?",
-	"A token of your devotion rests in your hands, ready to be placed upon the altar. What is it?",
-	"In your pocket is a lock of hair, plucked from the head of a loved one, ready to be offered to the abyss. What " +
-		"color and texture is the lock of hair?",
-	"As required for the ritual, you have prepared a small canvas from the shed skin of a viper. What is inscribed " +
-		"upon it?",
-	"In your shaking hand, you raise a mirror of polished silver in front of the altar. In it, you catch a glimpse " +
-		"of your own face. What expression does it show?",
-	"A vial of iridescent liquid, which you've harvested from a bioluminescent deep-sea creature, illuminates the " +
-		"summoning circle. What color does it glow?",
-	"You have formed a crude sculpture from a nearby spring of boiling mud, its fumes weaving an acrid olfactory " +
-		"tapestry. What is its appearance?",
-	"Your nostrils are filled with the fragrance of burning incense, which you've prepared from powdered bone and " +
-		"dried herbs. You hope it will serve its purpose in cleansing the altar. What fragrance does it produce?",
-	"With a chipped obsidian blade, you carve symbols of summoning into the barren earth. What do the symbols " +
-		"resemble?",
-	"You carefully place an effigy, crafted from the gnarled roots of a hanged man's tree, in its spot on the altar. " +
-		"What is the effigy's posture?",
-	"On a flute carved from the femur of a vulture, you play a haunting melody. What is its tempo?",
-	"A chalice, filled with the brackish water from a stagnant swamp, brims with the potential for otherworldly " +
-		"power. You gulp down as much as you can, hoping it is enough. What does it taste like?",
-	"You shed a single, perfect teardrop onto the summoning circle. What caused the tear to form?",
-	"You produce from your pack a tome of forbidden knowledge, crackling with eldritch energy. What is its title?",
-	"A single grain of sand, originating from the shores of a forgotten land, holds the weight of countless eons. " +
-		"Where did you find it?",
-	"With a pang of regret, you open up your hand to drop your most precious possession into the summoning circle. " +
-		"What is the object's texture?",
-	"The lingering scent of a nearly forgotten dream, which you've trapped within a sealed glass vial, gives power " +
-		"to the ritual. What was the dream about?",
-	"As an offering, you bring the preserved remains of a small creature to the altar. What part of the creature " +
-		"is missing?",
-	"A shard of bone, carved with intricate runes that hum with power, serves as a conduit for otherworldly " +
-		"energies. From what creature's bone did you take the shard?",
-	"A single drop of blood, drawn from the summoner's own finger, seals the pact with the entity being called " +
-		"forth. What is depicted on the handle of the knife you used to draw the blood?",
-	"A single word, which you whisper into the darkness, reverberates with the power to bridge the gap between " +
-		"worlds. What is the word?",
-	"A rusted iron nail, driven into the floor of the summoning circle, binds the entity to the physical realm. How " +
-		"many strikes of the hammer did it take you to secure it?",
-	"A piece of charcoal, which you used to draw the summoning circle upon the ground, crumbles into dust as the " +
-		"ritual nears completion. What shape is the charcoal?",
-}
@@ -0,0 +1,176 @@
+package pii
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Kind is a kind of personal information.
+type Kind string
+
+const (
+	EmailAddress         Kind = "email address"
+	PhoneNumber          Kind = "phone number"
+	StreetAddress        Kind = "street address"
+	CardNumber           Kind = "card number"
+	SocialSecurityNumber Kind = "social security number"
+	IpAddress            Kind = "IP address"
+)
+
+// Finding is a piece of personal information found in a text.
+type Finding struct {
+	Kind Kind
+
+	// Start and End are the byte offsets of the personal information in the text.
+	Start int
+	End   int
+}
+
+// detector finds one kind of personal information. If valid is not nil, it must return true for a match to count.
+type detector struct {
+	kind  Kind
+	regex *regexp.Regexp
+	valid func(match string) bool
+}
+
+// detectors contains the detectors for each kind of personal information, in order of precedence. A match is ignored
+// if it overlaps a match from an earlier detector.
+var detectors = []detector{
+	{
+		kind:  EmailAddress,
+		regex: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	{
+		kind:  CardNumber,
+		regex: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		valid: passesLuhnCheck,
+	},
+	{
+		kind:  SocialSecurityNumber,
+		regex: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	},
+	{
+		kind:  IpAddress,
+		regex: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),
+	},
+	{
+		// Phone numbers need a leading "+", an area code in brackets, or dashes or dots between their digits, so plain
+		// numbers such as "12345678" or "1692 1693 1694" aren't mistaken for them.
+		kind: PhoneNumber,
+		regex: regexp.MustCompile(`\+\d{1,3}[\s.-]?(?:\(\d{1,4}\)[\s.-]?)?\d{2,4}(?:[\s.-]?\d{2,4}){1,4}\b|` +
+			`\(\d{2,4}\)\s?\d{3,4}[\s.-]?\d{3,4}\b|\b\d{2,4}[.-]\d{3,4}(?:[.-]\d{3,4}){0,2}\b`),
+		valid: isPhoneNumber,
+	},
+	{
+		// House numbers may have a letter (as in "221B") or be a range (as in "12-14"). Phrases such as "12 feet of
+		// road" aren't mistaken for addresses, since their words can't be part of a street name.
+		kind: StreetAddress,
+		regex: regexp.MustCompile(`(?i)\b\d{1,6}[a-z]?(?:-\d{1,6}[a-z]?)?\s+(?:[a-z][a-z'.-]*\s+){1,3}` +
+			`(?:street|st|avenue|ave|road|rd|boulevard|blvd|lane|ln|drive|dr|court|ct|terrace|highway|hwy|parkway|` +
+			`pkwy)\b\.?`),
+		valid: isStreetAddress,
+	},
+}
+
+// Detect returns the personal information found in the given text, in the order it appears.
+func Detect(text string) []Finding {
+	var findings []Finding
+	for _, detector := range detectors {
+		for _, match := range detector.regex.FindAllStringIndex(text, -1) {
+			if detector.valid != nil && !detector.valid(text[match[0]:match[1]]) {
+				continue
+			}
+			overlaps := slices.ContainsFunc(findings, func(finding Finding) bool {
+				return match[0] < finding.End && finding.Start < match[1]
+			})
+			if !overlaps {
+				findings = append(findings, Finding{Kind: detector.kind, Start: match[0], End: match[1]})
+			}
+		}
+	}
+
+	slices.SortFunc(findings, func(a, b Finding) int { return a.Start - b.Start })
+	return findings
+}
+
+// Redact returns the given text with any personal information replaced by its kind in square brackets, such as
+// "[email address]", along with what was found.
+func Redact(text string) (string, []Finding) {
+	findings := Detect(text)
+
+	var redacted strings.Builder
+	end := 0
+	for _, finding := range findings {
+		redacted.WriteString(text[end:finding.Start])
+		redacted.WriteString("[" + string(finding.Kind) + "]")
+		end = finding.End
+	}
+	redacted.WriteString(text[end:])
+
+	return redacted.String(), findings
+}
+
+// groupedNumberRegex matches numbers written with their digits in groups of three, such as "1.000.000", which look
+// like phone numbers but aren't.
+var groupedNumberRegex = regexp.MustCompile(`^\d{1,3}(?:[ ,.]\d{3})+$`)
+
+// yearsRegex matches runs of years, such as "1692-1693", which look like phone numbers but aren't.
+var yearsRegex = regexp.MustCompile(`^(?:1\d|20)\d{2}(?:[\s.-](?:1\d|20)\d{2})+$`)
+
+// isPhoneNumber returns whether the given text, which looks like a phone number, has as many digits as a phone number
+// and isn't just a large number written in groups of three digits or a run of years.
+func isPhoneNumber(text string) bool {
+	digits := 0
+	for _, r := range text {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	return digits >= 7 && digits <= 15 && !groupedNumberRegex.MatchString(text) && !yearsRegex.MatchString(text)
+}
+
+// notStreetNameWords contains words that can follow a number but can't be part of a street name, such as units and
+// common function words.
+var notStreetNameWords = []string{"a", "an", "and", "at", "by", "down", "for", "from", "in", "of", "on", "or", "the",
+	"to", "up", "feet", "foot", "ft", "inches", "yards", "miles", "meters", "metres", "km", "kilometers",
+	"kilometres", "steps", "minutes", "hours", "days", "weeks", "months", "years", "times"}
+
+// isStreetAddress returns whether the given text, which looks like a street address, has a street name made up of
+// words that can be part of one.
+func isStreetAddress(text string) bool {
+	words := strings.Fields(strings.ToLower(text))
+	for _, word := range words[1 : len(words)-1] {
+		if slices.Contains(notStreetNameWords, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// passesLuhnCheck returns whether the digits in the given text pass the Luhn check used by card numbers.
+func passesLuhnCheck(text string) bool {
+	var digits []int
+	for _, r := range text {
+		if unicode.IsDigit(r) {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	for i := range digits {
+		digit := digits[len(digits)-1-i]
+		if i%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,76 @@
+package pii
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		kinds []Kind
+	}{
+		{"email address", "write to me at jane.doe+rituals@example.co.uk", []Kind{EmailAddress}},
+		{"phone number with dashes", "call 555-123-4567 tonight", []Kind{PhoneNumber}},
+		{"phone number with area code in brackets", "call (555) 123-4567", []Kind{PhoneNumber}},
+		{"international phone number", "ring +44 20 7946 0958", []Kind{PhoneNumber}},
+		{"number grouped with spaces", "a swarm of 100 000 000 moths", nil},
+		{"number grouped with dots", "1.000.000 candles", nil},
+		{"number grouped with commas", "1,000,000 candles", nil},
+		{"short number", "it has 12 legs and 400 teeth", nil},
+		{"local phone number", "call 555-1234", []Kind{PhoneNumber}},
+		{"number without separators", "it has 10000000 eyes", nil},
+		{"number followed by a word", "12345678 strikes", nil},
+		{"run of years", "in 1692 1693 1694", nil},
+		{"run of years with dashes", "from 1692-1693", nil},
+		{"card number", "4111 1111 1111 1111", []Kind{CardNumber}},
+		{"number failing the Luhn check", "4111 1111 1111 1112", nil},
+		{"social security number", "my number is 078-05-1120", []Kind{SocialSecurityNumber}},
+		{"IP address", "it lives at 192.168.0.1", []Kind{IpAddress}},
+		{"address with a street type that isn't known", "I live at 42 Wallaby Way", nil},
+		{"street address with a street type", "I live at 42 Elm Street", []Kind{StreetAddress}},
+		{"street address with a lettered house number", "221B Baker Street", []Kind{StreetAddress}},
+		{"street address with a house number range", "12-14 Elm Road", []Kind{StreetAddress}},
+		{"lowercase street address", "I live at 123 main street", []Kind{StreetAddress}},
+		{"lowercase street address with an abbreviated street type", "42 elm st", []Kind{StreetAddress}},
+		{"phrase that looks like an address", "12 feet of road", nil},
+		{"phrase with a unit that looks like an address", "2 hours drive away", nil},
+		{"several kinds", "jane@example.com or 555-123-4567", []Kind{EmailAddress, PhoneNumber}},
+		{"nothing personal", "a moth made of velvet and regret", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var kinds []Kind
+			for _, finding := range Detect(test.text) {
+				kinds = append(kinds, finding.Kind)
+			}
+			if !slices.Equal(kinds, test.kinds) {
+				t.Errorf("Detect(%q) found %v, want %v", test.text, kinds, test.kinds)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"nothing to redact", "a moth made of velvet", "a moth made of velvet"},
+		{"email address", "mail jane@example.com now", "mail [email address] now"},
+		{
+			"several kinds",
+			"jane@example.com, 555-123-4567, 221B Baker Street",
+			"[email address], [phone number], [street address]",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, _ := Redact(test.text); got != test.want {
+				t.Errorf("Redact(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package random
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"sync"
+)
+
+// Source creates the random number generators used by the game, all derived from a single seed, so that a run can be
+// reproduced by using the same seed again.
+type Source struct {
+	seed uint64
+}
+
+// NewSource creates a new Source with the given seed.
+func NewSource(seed uint64) Source {
+	return Source{seed: seed}
+}
+
+// NewSeed returns a new seed, chosen at random. It's never zero, since zero means no seed was chosen.
+func NewSeed() uint64 {
+	for {
+		if seed := rand.Uint64(); seed != 0 {
+			return seed
+		}
+	}
+}
+
+// Seed returns the source's seed.
+func (s Source) Seed() uint64 {
+	return s.seed
+}
+
+// Stream returns a random number generator for the given purpose, such as "prompts". Each purpose gets its own
+// generator, so that how often one part of the game draws random numbers (which may depend on timing) doesn't change
+// the numbers drawn by another. The same seed and purpose always produce the same numbers. The generator is safe for
+// concurrent use.
+func (s Source) Stream(purpose string) *rand.Rand {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(purpose))
+	return rand.New(&lockedSource{source: rand.NewPCG(s.seed, hash.Sum64())})
+}
+
+// lockedSource is a rand.Source that is safe for concurrent use.
+type lockedSource struct {
+	mutex  sync.Mutex
+	source rand.Source
+}
+
+// Uint64 implements rand.Source by returning a random number from the underlying source.
+func (s *lockedSource) Uint64() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.source.Uint64()
+}
@@ -24,11 +24,12 @@ type Background struct {
 	currentAnimationId int
 	currentWidth       int
 	currentHeight      int
+	random             *rand.Rand
 }
 
-// NewBackground returns a new Background.
-func NewBackground() Background {
-	return Background{}
+// NewBackground returns a new Background, which animates using the given random number generator.
+func NewBackground(random *rand.Rand) Background {
+	return Background{random: random}
 }
 
 // backgroundAnimationInterval is the rate at which the background is animated.
@@ -46,7 +47,7 @@ func (b Background) Init() tea.Cmd {
 	b.currentWidth = TerminalWidth
 	b.currentHeight = TerminalHeight
 	return func() tea.Msg {
-		return initializeCharacterTypes(b.currentAnimationId, TerminalWidth, TerminalHeight)
+		return initializeCharacterTypes(b.random, b.currentAnimationId, TerminalWidth, TerminalHeight)
 	}
 }
 
@@ -60,7 +61,7 @@ func (b Background) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			b.characterTypes = msg.characterTypes
 			b.characters = msg.characters
 			cmd = tea.Tick(backgroundAnimationInterval, func(t time.Time) tea.Msg {
-				return generateNextStep(msg.animationId, msg.characterTypes, msg.characters)
+				return generateNextStep(b.random, msg.animationId, msg.characterTypes, msg.characters)
 			})
 		}
 	case tea.WindowSizeMsg:
@@ -71,7 +72,7 @@ func (b Background) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			b.currentWidth = msg.Width
 			b.currentHeight = msg.Height
 			cmd = func() tea.Msg {
-				return initializeCharacterTypes(b.currentAnimationId, msg.Width, msg.Height)
+				return initializeCharacterTypes(b.random, b.currentAnimationId, msg.Width, msg.Height)
 			}
 		}
 	}
@@ -91,9 +92,9 @@ func (b Background) View() string {
 	return stringBuilder.String()
 }
 
-// initializeCharacterTypes initializes the character types to show in the background and returns them in a
-// characterTypesUpdateMsg.
-func initializeCharacterTypes(animationId, width, height int) tea.Msg {
+// initializeCharacterTypes initializes the character types to show in the background using the given random number
+// generator, and returns them in a characterTypesUpdateMsg.
+func initializeCharacterTypes(random *rand.Rand, animationId, width, height int) tea.Msg {
 	log.Logger.Print(fmt.Sprintf("func=\"ui.Background.initializeCharacterTypes\", msg=\"Function called.\", "+
 		"animationId=\"%d\", width=\"%d\", height=\"%d\"", animationId, width, height))
 
@@ -101,37 +102,38 @@ func initializeCharacterTypes(animationId, width, height int) tea.Msg {
 	for i := range characterTypes {
 		characterTypes[i] = make([]int, width)
 		for j := range characterTypes[i] {
-			characterTypes[i][j] = randomCharacterType()
+			characterTypes[i][j] = randomCharacterType(random)
 		}
 	}
 	return characterTypesUpdateMsg{
 		animationId:    animationId,
 		characterTypes: characterTypes,
-		characters:     generateCharactersForTypes(characterTypes, nil, nil),
+		characters:     generateCharactersForTypes(random, characterTypes, nil, nil),
 	}
 }
 
-// generateNextStep generates the next step of the cellular automaton.
-func generateNextStep(animationId int, characterTypes [][]int, characters [][]rune) tea.Msg {
+// generateNextStep generates the next step of the cellular automaton using the given random number generator.
+func generateNextStep(random *rand.Rand, animationId int, characterTypes [][]int, characters [][]rune) tea.Msg {
 	nextCharacterTypes := make([][]int, len(characterTypes))
 	for i := range characterTypes {
 		nextCharacterTypes[i] = make([]int, len(characterTypes[i]))
 		for j := range characterTypes[i] {
-			nextCharacterTypes[i][j] = nextCharacterType(characterTypes[i][j], getNeighbors(i, j, characterTypes))
+			nextCharacterTypes[i][j] = nextCharacterType(random, characterTypes[i][j],
+				getNeighbors(i, j, characterTypes))
 		}
 	}
 	return characterTypesUpdateMsg{
 		animationId:    animationId,
 		characterTypes: nextCharacterTypes,
-		characters:     generateCharactersForTypes(nextCharacterTypes, characterTypes, characters),
+		characters:     generateCharactersForTypes(random, nextCharacterTypes, characterTypes, characters),
 	}
 }
 
 // nextCharacterType returns the next character type by randomly selecting either the given character type or one of
 // its neighbors.
-func nextCharacterType(characterType int, neighbors []int) int {
+func nextCharacterType(random *rand.Rand, characterType int, neighbors []int) int {
 	neighbors = append(neighbors, characterType)
-	return neighbors[rand.IntN(len(neighbors))]
+	return neighbors[random.IntN(len(neighbors))]
 }
 
 // getNeighbors returns the horizontal, vertical, and diagonal neighbors of the given cell.
@@ -157,7 +159,7 @@ func getNeighbors(i, j int, characterTypes [][]int) []int {
 // generateCharactersForTypes generates characters based on the given character types. If the character type in a given
 // cell hasn't changed since the previous step, the character in that cell remains the same. Otherwise, a random
 // character of that type is chosen.
-func generateCharactersForTypes(characterTypes [][]int, previousCharacterTypes [][]int,
+func generateCharactersForTypes(random *rand.Rand, characterTypes [][]int, previousCharacterTypes [][]int,
 	previousCharacters [][]rune) [][]rune {
 
 	characters := make([][]rune, len(characterTypes))
@@ -167,7 +169,7 @@ func generateCharactersForTypes(characterTypes [][]int, previousCharacterTypes [
 			if previousCharacterTypes != nil && previousCharacterTypes[i][j] == characterType {
 				characters[i][j] = previousCharacters[i][j]
 			} else {
-				characters[i][j] = randomCharacterOfType(characterType)
+				characters[i][j] = randomCharacterOfType(random, characterType)
 			}
 		}
 	}
@@ -175,12 +177,12 @@ func generateCharactersForTypes(characterTypes [][]int, previousCharacterTypes [
 }
 
 // randomCharacterType returns a random character type.
-func randomCharacterType() int {
-	return rand.IntN(len(availableCharacterTypes))
+func randomCharacterType(random *rand.Rand) int {
+	return random.IntN(len(availableCharacterTypes))
 }
 
 // randomCharacterOfType returns a random character of the given type.
-func randomCharacterOfType(characterType int) rune {
+func randomCharacterOfType(random *rand.Rand, characterType int) rune {
 	numCharactersOfType := len(availableCharacterTypes[characterType])
-	return availableCharacterTypes[characterType][rand.IntN(numCharactersOfType)]
+	return availableCharacterTypes[characterType][random.IntN(numCharactersOfType)]
 }
@@ -0,0 +1,88 @@
+package ui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/audio"
+	"strings"
+)
+
+// ChoiceOption is one of the options of a Choice.
+type ChoiceOption struct {
+	// Value identifies the option. It's the response sent when the option is chosen.
+	Value string
+
+	// Label is the text shown to the player.
+	Label string
+}
+
+// Choice is a UI component that lets the player choose one of several options, using the arrow keys. It implements
+// tea.Model.
+type Choice struct {
+	id       int
+	options  []ChoiceOption
+	selected int
+	enabled  bool
+}
+
+// NewChoice creates a new Choice with the given options. The first option is selected to begin with.
+func NewChoice(id int, options ...ChoiceOption) Choice {
+	return Choice{
+		id:      id,
+		options: options,
+	}
+}
+
+// ChoiceSetEnabledMsg is a tea.Msg used to indicate that the choice with the given ID should be enabled or disabled.
+type ChoiceSetEnabledMsg struct {
+	Id      int
+	Enabled bool
+}
+
+// Init implements tea.Model by returning nil.
+func (c Choice) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model by moving the selection when the arrow keys are pressed.
+func (c Choice) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case ChoiceSetEnabledMsg:
+		if msg.Id == c.id {
+			c.enabled = msg.Enabled
+		}
+	case tea.KeyMsg:
+		if !c.enabled || len(c.options) == 0 {
+			return c, nil
+		}
+		switch msg.Type {
+		case tea.KeyUp, tea.KeyLeft, tea.KeyShiftTab:
+			c.selected = (c.selected + len(c.options) - 1) % len(c.options)
+			_ = audio.Play(audio.TapSoundEffect, nil, true)
+		case tea.KeyDown, tea.KeyRight, tea.KeyTab:
+			c.selected = (c.selected + 1) % len(c.options)
+			_ = audio.Play(audio.TapSoundEffect, nil, true)
+		}
+	}
+	return c, nil
+}
+
+// View implements tea.Model by returning the options, one per line, with the selected option marked.
+func (c Choice) View() string {
+	lines := make([]string, len(c.options))
+	for i, option := range c.options {
+		if i == c.selected {
+			lines[i] = "> " + option.Label
+		} else {
+			lines[i] = "  " + option.Label
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Value returns the value of the selected option, or an empty string if there are no options.
+func (c Choice) Value() string {
+	if len(c.options) == 0 {
+		return ""
+	}
+	return c.options[c.selected].Value
+}
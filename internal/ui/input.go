@@ -7,6 +7,9 @@ import (
 	"unicode"
 )
 
+// inputCharLimit is the maximum number of characters the player can enter.
+const inputCharLimit = 200
+
 // Input is a UI component that accepts text input from the player. It implements tea.Model.
 type Input struct {
 	id           int
@@ -16,6 +19,7 @@ type Input struct {
 // NewInput creates a new Input with the given text input model.
 func NewInput(id int) Input {
 	backingInput := textinput.New()
+	backingInput.CharLimit = inputCharLimit
 	return Input{
 		id:           id,
 		backingInput: backingInput,
@@ -13,7 +13,9 @@ import (
 var ansiControlSequenceIntroducer = string([]rune{rune(ansi.ESC), '['})
 var ansiResetStyle = ansiControlSequenceIntroducer + "0m"
 var ansiInverse = ansiControlSequenceIntroducer + "7m"
-var ansiBackgroundStyle, _ = strings.CutSuffix(BackgroundStyle.Render(), ansiResetStyle)
+
+// ansiBackgroundStyle is the ANSI style of BackgroundStyle. It is set by SetPalette.
+var ansiBackgroundStyle string
 
 // ansiOrSingleSpaceRegex matches a single ANSI control sequence.
 var singleAnsiRegex, _ = regexp.Compile(string(rune(ansi.ESC)) + "\\[(\\d+;)*\\d*[a-zA-Z]")
@@ -6,7 +6,9 @@ import (
 	"github.com/charmbracelet/x/ansi"
 	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/audio"
 	"math/rand/v2"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // Message is a UI component that displays a message to the player. It implements tea.Model.
@@ -17,18 +19,57 @@ type Message struct {
 	responseReceived         bool
 	charactersRendered       int
 	useBuzzForScrollingSound bool
+	streaming                bool
+	waitingForText           bool
+	highlights               []Highlight
+	notesHint                string
+	showingNotes             bool
+	random                   *rand.Rand
 }
 
-// NewMessage creates a new Message.
-func NewMessage(id int, text string, responseComponent tea.Model) Message {
+// Highlight marks part of a message's text, which is rendered in the accent color. The player can toggle a note for
+// each highlight, explaining where the highlighted text came from.
+type Highlight struct {
+	// Start and End are the offsets of the highlighted text, in characters rather than bytes.
+	Start int
+	End   int
+
+	Note string
+}
+
+// NewMessage creates a new Message, which chooses its sound effects using the given random number generator.
+func NewMessage(id int, text string, responseComponent tea.Model, random *rand.Rand) Message {
 	return Message{
 		id:                       id,
 		text:                     text,
 		responseComponent:        responseComponent,
-		useBuzzForScrollingSound: rand.IntN(2) == 0,
+		useBuzzForScrollingSound: random.IntN(2) == 0,
+		random:                   random,
 	}
 }
 
+// NewStreamingMessage creates a new Message whose text arrives over time, through MessageStreamMsg. The message plays
+// its text as it arrives, and the response component isn't shown until the final text has arrived.
+func NewStreamingMessage(id int, responseComponent tea.Model, random *rand.Rand) Message {
+	message := NewMessage(id, "", responseComponent, random)
+	message.streaming = true
+	return message
+}
+
+// WithHighlights returns a copy of the message with the given highlights, which must be in order of position.
+// Highlights that fall outside the message's text are ignored.
+func (m Message) WithHighlights(highlights []Highlight) Message {
+	m.highlights = highlights
+	return m
+}
+
+// WithNotesHint returns a copy of the message that shows the given hint in place of its response component, once it
+// has been fully rendered, if it has any highlights. The hint should tell the player how to toggle the notes.
+func (m Message) WithNotesHint(hint string) Message {
+	m.notesHint = hint
+	return m
+}
+
 // playInterval is the rate at which the message plays.
 const playInterval = 10 * time.Millisecond
 
@@ -37,6 +78,16 @@ type MessageResponseMsg struct {
 	Response string
 }
 
+// MessageStreamMsg is a tea.Msg used to update the text of the streaming message with the given ID. The text replaces
+// the message's current text, and the highlights replace its current highlights. If Done is true, no more text will
+// arrive.
+type MessageStreamMsg struct {
+	Id         int
+	Text       string
+	Highlights []Highlight
+	Done       bool
+}
+
 // nextCharMsg is a tea.Msg used to tell the model to play the next character.
 type nextCharMsg struct {
 	id int
@@ -44,10 +95,7 @@ type nextCharMsg struct {
 
 // Init implements tea.Model by returning a tea.Cmd that schedules a nextCharMsg.
 func (m Message) Init() tea.Cmd {
-	tickCmd := tea.Tick(playInterval, func(t time.Time) tea.Msg {
-		return nextCharMsg{id: m.id}
-	})
-	return tea.Batch(tickCmd, m.responseComponent.Init())
+	return tea.Batch(m.scheduleNextChar(), m.responseComponent.Init())
 }
 
 // Update implements tea.Model by updating the model based on the given message.
@@ -55,15 +103,19 @@ func (m Message) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case nextCharMsg:
 		if msg.id == m.id {
-			m.charactersRendered = min(m.charactersRendered+1, len(m.text))
-			m.playScrollingSoundEffect()
+			textLength := m.textLength()
+			if m.charactersRendered < textLength {
+				m.charactersRendered++
+				m.playScrollingSoundEffect()
+			}
 
 			var cmd tea.Cmd
-			if m.charactersRendered < len(m.text) {
+			if m.charactersRendered < textLength {
 				// Not all characters have been rendered yet, so schedule another tick.
-				cmd = tea.Tick(playInterval, func(t time.Time) tea.Msg {
-					return nextCharMsg{id: m.id}
-				})
+				cmd = m.scheduleNextChar()
+			} else if m.streaming {
+				// All the text received so far has been rendered, so wait for more to arrive.
+				m.waitingForText = true
 			} else {
 				if m.useBuzzForScrollingSound {
 					audio.ResetLastSegmentPlayed(audio.DoubleBuzzSoundEffect)
@@ -71,20 +123,46 @@ func (m Message) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					audio.ResetLastSegmentPlayed(audio.RhythmicClicksAndBuzzesSoundEffect)
 				}
 
-				// The message has been fully rendered, so if the response component is an input component, allow it to
-				// begin receiving input.
-				if _, ok := m.responseComponent.(Input); ok {
+				// The message has been fully rendered, so if the response component is an input or choice component,
+				// allow it to begin receiving input.
+				switch m.responseComponent.(type) {
+				case Input:
 					cmd = func() tea.Msg { return InputSetEnabledMsg{Id: m.id, Enabled: true} }
+				case Choice:
+					cmd = func() tea.Msg { return ChoiceSetEnabledMsg{Id: m.id, Enabled: true} }
 				}
 			}
 			return m, cmd
 		}
+	case MessageStreamMsg:
+		if msg.Id == m.id && m.streaming {
+			m.text = msg.Text
+			m.highlights = msg.Highlights
+			m.streaming = !msg.Done
+			m.charactersRendered = min(m.charactersRendered, m.textLength())
+
+			var cmd tea.Cmd
+			if m.waitingForText {
+				m.waitingForText = false
+				cmd = m.scheduleNextChar()
+			}
+			return m, cmd
+		}
 	case tea.KeyMsg:
+		// If the player presses Tab after the message has been fully rendered, toggle the notes of its highlights.
+		if msg.Type == tea.KeyTab && !m.responseReceived && len(m.highlights) > 0 &&
+			m.charactersRendered == m.textLength() && !m.streaming {
+
+			m.showingNotes = !m.showingNotes
+			_ = audio.Play(getRandomBeepSoundEffect(m.random), nil, false)
+			return m, nil
+		}
+
 		// If the presses Enter after the message has been fully rendered, send the response.
 		if msg.Type == tea.KeyEnter && !m.responseReceived {
-			if m.charactersRendered != len(m.text) {
+			if m.charactersRendered != m.textLength() || m.streaming {
 				// The message has not yet been fully rendered. Just skip to the end of the animation.
-				m.charactersRendered = len(m.text)
+				m.charactersRendered = m.textLength()
 				return m, func() tea.Msg { return nextCharMsg{id: m.id} }
 			}
 
@@ -101,8 +179,14 @@ func (m Message) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmd = func() tea.Msg { return InputSetEnabledMsg{Id: m.id, Enabled: false} }
 			}
 
+			// If the response component is a choice component, get the chosen option and disable it.
+			if choice, ok := m.responseComponent.(Choice); ok {
+				response = choice.Value()
+				cmd = func() tea.Msg { return ChoiceSetEnabledMsg{Id: m.id, Enabled: false} }
+			}
+
 			m.responseReceived = true
-			_ = audio.Play(getRandomBeepSoundEffect(), nil, false)
+			_ = audio.Play(getRandomBeepSoundEffect(m.random), nil, false)
 
 			cmd = tea.Batch(
 				cmd,
@@ -121,15 +205,27 @@ func (m Message) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Message) View() string {
 	runes := []rune(m.text)
 	visibleText := string(runes[:m.charactersRendered])
+	if !m.responseReceived {
+		visibleText = m.highlight(runes[:m.charactersRendered])
+	}
 	view := ansi.Wrap(visibleText, TerminalWidth, "")
 
+	if m.showingNotes && !m.responseReceived {
+		view = lipgloss.JoinVertical(lipgloss.Left, view, "", m.notesView())
+	}
+
 	if m.responseReceived {
-		if _, ok := m.responseComponent.(Input); ok {
+		switch m.responseComponent.(type) {
+		case Input, Choice:
 			view = lipgloss.JoinVertical(lipgloss.Left, view, m.responseComponent.View())
 		}
 		view = InactiveTextStyle.Render(view)
-	} else if m.charactersRendered == len(m.text) {
-		response := ansi.Wrap(m.responseComponent.View(), TerminalWidth, "")
+	} else if m.charactersRendered == m.textLength() && !m.streaming {
+		response := m.responseComponent.View()
+		if len(m.highlights) > 0 && len(m.notesHint) > 0 {
+			response = m.notesHint
+		}
+		response = ansi.Wrap(response, TerminalWidth, "")
 		response = SecondaryTextStyle.Render(response)
 		view = lipgloss.JoinVertical(lipgloss.Left, view, response)
 	}
@@ -139,6 +235,78 @@ func (m Message) View() string {
 		Render(view)
 }
 
+// highlight returns the given characters of the message's text, with the highlighted parts in the accent color. The
+// rest is styled explicitly, since the end of each highlight resets the style of the text that follows it.
+func (m Message) highlight(runes []rune) string {
+	if len(m.highlights) == 0 {
+		return string(runes)
+	}
+
+	var builder strings.Builder
+	position := 0
+	for _, highlight := range m.highlights {
+		start := max(highlight.Start, position)
+		end := min(highlight.End, len(runes))
+		if start >= end {
+			continue
+		}
+		if start > position {
+			builder.WriteString(PrimaryTextStyle.Render(string(runes[position:start])))
+		}
+		builder.WriteString(styleWords(AccentTextStyle, string(runes[start:end])))
+		position = end
+	}
+	if position < len(runes) {
+		builder.WriteString(PrimaryTextStyle.Render(string(runes[position:])))
+	}
+	return builder.String()
+}
+
+// notesView returns the notes of the message's highlights, each below the highlighted text it explains.
+func (m Message) notesView() string {
+	runes := []rune(m.text)
+	var notes []string
+	for _, highlight := range m.highlights {
+		if highlight.Start < 0 || highlight.Start >= highlight.End || highlight.End > len(runes) {
+			continue
+		}
+		note := styleWords(AccentTextStyle, string(runes[highlight.Start:highlight.End])) +
+			styleWords(PrimaryTextStyle, " ← ") + styleWords(SecondaryTextStyle, highlight.Note)
+		notes = append(notes, ansi.Wrap(note, TerminalWidth, ""))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, notes...)
+}
+
+// styleWords renders each word of the given text with the given style, along with the spaces that follow it. Styling
+// each word separately means the style isn't lost when the text is wrapped onto several lines.
+func styleWords(style lipgloss.Style, text string) string {
+	var builder strings.Builder
+	for len(text) > 0 {
+		end := strings.IndexByte(text, ' ')
+		if end == -1 {
+			end = len(text)
+		}
+		for end < len(text) && text[end] == ' ' {
+			end++
+		}
+		builder.WriteString(style.Render(text[:end]))
+		text = text[end:]
+	}
+	return builder.String()
+}
+
+// textLength returns the number of characters in the message's text.
+func (m Message) textLength() int {
+	return utf8.RuneCountInString(m.text)
+}
+
+// scheduleNextChar returns a tea.Cmd that schedules a nextCharMsg.
+func (m Message) scheduleNextChar() tea.Cmd {
+	return tea.Tick(playInterval, func(t time.Time) tea.Msg {
+		return nextCharMsg{id: m.id}
+	})
+}
+
 // playScrollingSoundEffect plays the scrolling sound effect.
 func (m Message) playScrollingSoundEffect() {
 	if m.useBuzzForScrollingSound {
@@ -159,12 +327,13 @@ func getNextAudioSegmentIndexForScrolling(filename audio.SoundEffectFilename, nu
 	return (lastIndex % numSegments) + 1
 }
 
-// getRandomBeepSoundEffect returns a random beep sound effect by its filename.
-func getRandomBeepSoundEffect() audio.SoundEffectFilename {
+// getRandomBeepSoundEffect returns a random beep sound effect by its filename, chosen using the given random number
+// generator.
+func getRandomBeepSoundEffect(random *rand.Rand) audio.SoundEffectFilename {
 	availableSoundEffects := []audio.SoundEffectFilename{
 		audio.HighPitchedBeepSoundEffect,
 		audio.LongLowPitchedBeepSoundEffect,
 		audio.ShortLowPitchedBeepSoundEffect,
 	}
-	return availableSoundEffects[rand.IntN(len(availableSoundEffects))]
+	return availableSoundEffects[random.IntN(len(availableSoundEffects))]
 }
@@ -2,32 +2,110 @@ package ui
 
 import (
 	"github.com/charmbracelet/lipgloss"
+	"strings"
 )
 
-const (
-	backgroundColor          = lipgloss.Color("#0F0114")
-	backgroundAnimationColor = lipgloss.Color("#3A042B")
-	textColor                = lipgloss.Color("#FFFFFF")
-	secondaryTextColor       = lipgloss.Color("#FF2626")
-	inactiveTextColor        = lipgloss.Color("#6A4D4D")
-)
+// Palette contains the colors used when rendering.
+type Palette struct {
+	BackgroundColor          lipgloss.Color
+	BackgroundAnimationColor lipgloss.Color
+	TextColor                lipgloss.Color
+	SecondaryTextColor       lipgloss.Color
+	InactiveTextColor        lipgloss.Color
+
+	// AccentTextColor is a subtle variation of TextColor, used for highlighted text.
+	AccentTextColor lipgloss.Color
+}
+
+// DefaultPalette is the palette used unless another one is set.
+var DefaultPalette = Palette{
+	BackgroundColor:          lipgloss.Color("#0F0114"),
+	BackgroundAnimationColor: lipgloss.Color("#3A042B"),
+	TextColor:                lipgloss.Color("#FFFFFF"),
+	SecondaryTextColor:       lipgloss.Color("#FF2626"),
+	InactiveTextColor:        lipgloss.Color("#6A4D4D"),
+	AccentTextColor:          lipgloss.Color("#F5A3B8"),
+}
+
+// palettes contains the palettes that can be set by name with SetPaletteByName.
+var palettes = map[string]Palette{
+	"crimson": DefaultPalette,
+	"storybook": {
+		BackgroundColor:          lipgloss.Color("#0B1A12"),
+		BackgroundAnimationColor: lipgloss.Color("#1F4A2E"),
+		TextColor:                lipgloss.Color("#F4EBD0"),
+		SecondaryTextColor:       lipgloss.Color("#E8B64C"),
+		InactiveTextColor:        lipgloss.Color("#6B7A5E"),
+		AccentTextColor:          lipgloss.Color("#C9E4A8"),
+	},
+	"phosphor": {
+		BackgroundColor:          lipgloss.Color("#020A02"),
+		BackgroundAnimationColor: lipgloss.Color("#0E3B12"),
+		TextColor:                lipgloss.Color("#9CFF8A"),
+		SecondaryTextColor:       lipgloss.Color("#FF4FD8"),
+		InactiveTextColor:        lipgloss.Color("#3F6B3A"),
+		AccentTextColor:          lipgloss.Color("#D6FF7A"),
+	},
+	"memo": {
+		BackgroundColor:          lipgloss.Color("#10151C"),
+		BackgroundAnimationColor: lipgloss.Color("#26303D"),
+		TextColor:                lipgloss.Color("#E6E9ED"),
+		SecondaryTextColor:       lipgloss.Color("#5FA8F5"),
+		InactiveTextColor:        lipgloss.Color("#5A6572"),
+		AccentTextColor:          lipgloss.Color("#A9D1FF"),
+	},
+	"crayon": {
+		BackgroundColor:          lipgloss.Color("#1A1033"),
+		BackgroundAnimationColor: lipgloss.Color("#3B2A6B"),
+		TextColor:                lipgloss.Color("#FFF6E0"),
+		SecondaryTextColor:       lipgloss.Color("#FF9F1C"),
+		InactiveTextColor:        lipgloss.Color("#7A6C99"),
+		AccentTextColor:          lipgloss.Color("#B6F0FF"),
+	},
+}
 
 var TerminalWidth int
 var TerminalHeight int
 
-var BackgroundStyle = lipgloss.NewStyle().
-	Background(backgroundColor).
-	Foreground(backgroundAnimationColor)
+var BackgroundStyle lipgloss.Style
 
-var PrimaryTextStyle = BackgroundStyle.Foreground(textColor)
+var PrimaryTextStyle lipgloss.Style
 
-var SecondaryTextStyle = BackgroundStyle.Foreground(secondaryTextColor)
+var SecondaryTextStyle lipgloss.Style
 
-var InactiveTextStyle = BackgroundStyle.Foreground(inactiveTextColor)
+var InactiveTextStyle lipgloss.Style
 
-var FullScreenStyle = BackgroundStyle.
-	Width(TerminalWidth).
-	Height(TerminalHeight)
+var AccentTextStyle lipgloss.Style
+
+var FullScreenStyle lipgloss.Style
+
+func init() {
+	SetPalette(DefaultPalette)
+}
+
+// SetPalette sets the colors used when rendering. It must be called before the UI is started.
+func SetPalette(palette Palette) {
+	BackgroundStyle = lipgloss.NewStyle().
+		Background(palette.BackgroundColor).
+		Foreground(palette.BackgroundAnimationColor)
+	PrimaryTextStyle = BackgroundStyle.Foreground(palette.TextColor)
+	SecondaryTextStyle = BackgroundStyle.Foreground(palette.SecondaryTextColor)
+	InactiveTextStyle = BackgroundStyle.Foreground(palette.InactiveTextColor)
+	AccentTextStyle = BackgroundStyle.Foreground(palette.AccentTextColor)
+	FullScreenStyle = BackgroundStyle.
+		Width(TerminalWidth).
+		Height(TerminalHeight)
+	ansiBackgroundStyle, _ = strings.CutSuffix(BackgroundStyle.Render(), ansiResetStyle)
+}
+
+// SetPaletteByName sets the palette with the given name, returning false if there is no such palette.
+func SetPaletteByName(name string) bool {
+	palette, ok := palettes[name]
+	if ok {
+		SetPalette(palette)
+	}
+	return ok
+}
 
 // UpdateTerminalSize updates the terminal size used when rendering.
 func UpdateTerminalSize(w, h int) {
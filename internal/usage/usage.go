@@ -0,0 +1,194 @@
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/config"
+	"github.com/colececil/the-floppy-disk-of-forbidden-creatures/internal/log"
+	"io/fs"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Usage is the number of tokens used by a completion.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Totals are the combined usage and estimated cost of a number of completions.
+type Totals struct {
+	Completions      int `json:"completions"`
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+
+	// EstimatedCost is in US dollars. Completions by models with no known price are counted as free.
+	EstimatedCost float64 `json:"estimatedCost"`
+}
+
+// add adds a completion with the given usage and estimated cost to the totals.
+func (t *Totals) add(usage Usage, cost float64) {
+	t.Completions++
+	t.PromptTokens += usage.PromptTokens
+	t.CompletionTokens += usage.CompletionTokens
+	t.EstimatedCost += cost
+}
+
+// Stats are the totals of a number of completions, both overall and broken down by task and by model.
+type Stats struct {
+	Totals
+	ByTask  map[string]Totals `json:"byTask"`
+	ByModel map[string]Totals `json:"byModel"`
+}
+
+// add adds a completion for the given task and model, with the given usage and estimated cost, to the stats.
+func (s *Stats) add(task, model string, usage Usage, cost float64) {
+	s.Totals.add(usage, cost)
+
+	if s.ByTask == nil {
+		s.ByTask = make(map[string]Totals)
+	}
+	taskTotals := s.ByTask[task]
+	taskTotals.add(usage, cost)
+	s.ByTask[task] = taskTotals
+
+	if s.ByModel == nil {
+		s.ByModel = make(map[string]Totals)
+	}
+	modelTotals := s.ByModel[model]
+	modelTotals.add(usage, cost)
+	s.ByModel[model] = modelTotals
+}
+
+// clone returns a copy of the stats that doesn't share its maps.
+func (s Stats) clone() Stats {
+	s.ByTask = maps.Clone(s.ByTask)
+	s.ByModel = maps.Clone(s.ByModel)
+	return s
+}
+
+// statsFile is the contents of the usage statistics file.
+type statsFile struct {
+	// Sessions is the number of sessions that have used a backend.
+	Sessions int `json:"sessions"`
+
+	Cumulative  Stats `json:"cumulative"`
+	LastSession Stats `json:"lastSession"`
+}
+
+// defaultPrices are the built-in prices of OpenAI's models, in US dollars per million tokens.
+var defaultPrices = map[string]config.Price{
+	"gpt-3.5-turbo": {Input: 0.5, Output: 1.5},
+	"gpt-4":         {Input: 30, Output: 60},
+	"gpt-4-turbo":   {Input: 10, Output: 30},
+	"gpt-4o":        {Input: 5, Output: 15},
+	"gpt-4o-mini":   {Input: 0.15, Output: 0.6},
+}
+
+// Tracker keeps track of the tokens used by the backend and their estimated cost, for the current session and for all
+// sessions combined, and writes them to the usage statistics file. It's safe for concurrent use.
+type Tracker struct {
+	mutex         sync.Mutex
+	path          string
+	prices        map[string]config.Price
+	budget        float64
+	sessionBudget float64
+	file          statsFile
+	session       Stats
+}
+
+// NewTracker creates a new Tracker described by the given configuration. The totals of earlier sessions are read from
+// the usage statistics file, if it exists.
+func NewTracker(usageConfig config.Usage) (*Tracker, error) {
+	prices := maps.Clone(defaultPrices)
+	maps.Copy(prices, usageConfig.Prices)
+
+	tracker := &Tracker{
+		path:          usageConfig.StatsFile,
+		prices:        prices,
+		budget:        usageConfig.Budget,
+		sessionBudget: usageConfig.SessionBudget,
+	}
+
+	data, err := os.ReadFile(usageConfig.StatsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return tracker, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &tracker.file); err != nil {
+		return nil, fmt.Errorf("invalid usage statistics file %q: %w", usageConfig.StatsFile, err)
+	}
+	return tracker, nil
+}
+
+// Record adds a completion for the given task by the given model, with the given usage, to the totals, and writes the
+// usage statistics file.
+func (t *Tracker) Record(task, model string, usage Usage) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	cost := t.estimateCost(model, usage)
+	if t.session.Completions == 0 {
+		t.file.Sessions++
+	}
+	t.session.add(task, model, usage, cost)
+	t.file.Cumulative.add(task, model, usage, cost)
+	t.file.LastSession = t.session
+
+	log.Logger.Print(fmt.Sprintf("func=\"usage.Tracker.Record\", msg=\"Usage recorded.\", task=\"%s\", "+
+		"model=\"%s\", promptTokens=\"%d\", completionTokens=\"%d\", estimatedCost=\"%.6f\", "+
+		"sessionCost=\"%.6f\", cumulativeCost=\"%.6f\"", task, model, usage.PromptTokens, usage.CompletionTokens,
+		cost, t.session.EstimatedCost, t.file.Cumulative.EstimatedCost))
+
+	data, err := json.MarshalIndent(t.file, "", "  ")
+	if err == nil {
+		err = os.WriteFile(t.path, data, 0644)
+	}
+	if err != nil {
+		log.Logger.Print(fmt.Sprintf("func=\"usage.Tracker.Record\", msg=\"Failed to write usage statistics "+
+			"file.\", path=\"%s\", error=\"%v\"", t.path, err))
+	}
+}
+
+// Session returns the stats of the current session.
+func (t *Tracker) Session() Stats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.session.clone()
+}
+
+// Cumulative returns the stats of all sessions combined, including the current one.
+func (t *Tracker) Cumulative() Stats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.file.Cumulative.clone()
+}
+
+// OverBudget returns whether the estimated cost of the current session, or of all sessions combined, has reached its
+// budget.
+func (t *Tracker) OverBudget() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return (t.budget > 0 && t.file.Cumulative.EstimatedCost >= t.budget) ||
+		(t.sessionBudget > 0 && t.session.EstimatedCost >= t.sessionBudget)
+}
+
+// estimateCost returns the estimated cost of a completion by the given model with the given usage, in US dollars. The
+// price used is the one whose key is the longest prefix of the model's name. If there's no such price, the cost is
+// zero.
+func (t *Tracker) estimateCost(model string, usage Usage) float64 {
+	var price config.Price
+	matchLength := -1
+	for name, candidate := range t.prices {
+		if strings.HasPrefix(model, name) && len(name) > matchLength {
+			price = candidate
+			matchLength = len(name)
+		}
+	}
+	return (float64(usage.PromptTokens)*price.Input + float64(usage.CompletionTokens)*price.Output) / 1_000_000
+}